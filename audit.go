@@ -0,0 +1,92 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// auditContextKey is a private key for storing AuditInfo in the context.
+type auditContextKey struct{}
+
+// AuditInfo describes who made a change and why. It is carried by the context of
+// a store call and is not interpreted by the store itself.
+type AuditInfo struct {
+	Actor  string
+	Reason string
+}
+
+// WithAuditInfo returns a new context carrying the actor and reason of the changes made
+// with it. Mutation hooks can retrieve them with AuditInfoFrom, e.g. to write a change log.
+func WithAuditInfo(ctx context.Context, actor, reason string) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, AuditInfo{Actor: actor, Reason: reason})
+}
+
+// AuditInfoFrom retrieves the AuditInfo attached to ctx with WithAuditInfo, if any.
+func AuditInfoFrom(ctx context.Context) (AuditInfo, bool) {
+	info, ok := ctx.Value(auditContextKey{}).(AuditInfo)
+	return info, ok
+}
+
+// MutationOp identifies the kind of change reported to a MutationHook.
+type MutationOp string
+
+// Operations reported to a MutationHook.
+const (
+	MutationSave   MutationOp = "save"
+	MutationDelete MutationOp = "delete"
+	MutationRekey  MutationOp = "rekey"
+	MutationUpdate MutationOp = "update"
+)
+
+// Mutation describes a change made to a single entity.
+type Mutation struct {
+	Op  MutationOp
+	Key string
+	// OldKey is the previous key of an entity changed by Rekey. It is empty for other operations.
+	OldKey string
+}
+
+// MutationHook is called after each successful change to a single entity, with the context
+// of the call that made it. If ctx carries a transaction, the hook runs within it, so a hook
+// that writes to the database, e.g. an audit table, commits or rolls back with the change.
+// An error returned by the hook is returned by the store method; the change itself is only
+// undone if it was made within a transaction that is then rolled back.
+type MutationHook func(ctx context.Context, m Mutation) error
+
+// WithMutationHook sets a function that is called after every Save, Delete, Rekey,
+// UpdatePaths, UpdateUnset and MergePatch, for every record written by Import and for every entity
+// deleted by DeleteReturning. Bulk operations that do not address entities by key, such
+// as UpdateWhere, are not reported.
+func WithMutationHook(hook MutationHook) StoreOption {
+	return func(config *storeConfig) {
+		config.mutationHook = hook
+	}
+}
+
+// notifyIfChanged is like notify, but skips statements that changed no rows, such as
+// deleting a missing key or a Save ignored under ConflictIgnore.
+func (s *Store[T]) notifyIfChanged(ctx context.Context, res sql.Result, m Mutation) error {
+	if s.mutationHook == nil {
+		return nil
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking rows affected by %s of key %s: %w", m.Op, m.Key, err)
+	}
+	if n == 0 {
+		return nil
+	}
+	return s.notify(ctx, m)
+}
+
+// notify reports m to the configured mutation hook, if any.
+func (s *Store[T]) notify(ctx context.Context, m Mutation) error {
+	if s.mutationHook == nil {
+		return nil
+	}
+	if err := s.mutationHook(ctx, m); err != nil {
+		return fmt.Errorf("mutation hook for %s of key %s: %w", m.Op, m.Key, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package litestore_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_WithMutationHook(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type auditEntry struct {
+		op     litestore.MutationOp
+		key    string
+		oldKey string
+		actor  string
+		reason string
+	}
+	var entries []auditEntry
+	errHook := errors.New("audit log unavailable")
+	failHook := false
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_audit",
+		litestore.WithMutationHook(func(ctx context.Context, m litestore.Mutation) error {
+			if failHook {
+				return errHook
+			}
+			info, _ := litestore.AuditInfoFrom(ctx)
+			entries = append(entries, auditEntry{op: m.Op, key: m.Key, oldKey: m.OldKey, actor: info.Actor, reason: info.Reason})
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	t.Run("mutations are reported with audit info", func(t *testing.T) {
+		entries = nil
+		auditCtx := litestore.WithAuditInfo(ctx, "admin", "cleanup")
+
+		entity := &TestPersonWithKey{K: "a", Name: "alice"}
+		if err := s.Save(auditCtx, entity); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if err := s.UpdatePaths(auditCtx, "a", map[string]any{"value": 1}); err != nil {
+			t.Fatalf("UpdatePaths failed: %v", err)
+		}
+		if err := s.Rekey(auditCtx, "a", "b"); err != nil {
+			t.Fatalf("Rekey failed: %v", err)
+		}
+		if err := s.Delete(auditCtx, "b"); err != nil {
+			t.Fatalf("failed to delete entity: %v", err)
+		}
+		// Deleting a missing key changes nothing and is not reported.
+		if err := s.Delete(ctx, "missing"); err != nil {
+			t.Fatalf("failed to delete entity: %v", err)
+		}
+
+		expected := []auditEntry{
+			{op: litestore.MutationSave, key: "a", actor: "admin", reason: "cleanup"},
+			{op: litestore.MutationUpdate, key: "a", actor: "admin", reason: "cleanup"},
+			{op: litestore.MutationRekey, key: "b", oldKey: "a", actor: "admin", reason: "cleanup"},
+			{op: litestore.MutationDelete, key: "b", actor: "admin", reason: "cleanup"},
+		}
+		if !reflect.DeepEqual(entries, expected) {
+			t.Errorf("unexpected audit entries.\ngot:  %+v\nwant: %+v", entries, expected)
+		}
+	})
+
+	t.Run("hook error rolls back the transaction", func(t *testing.T) {
+		failHook = true
+		defer func() { failHook = false }()
+
+		err := litestore.WithTransaction(ctx, db, func(txCtx context.Context) error {
+			return s.Save(txCtx, &TestPersonWithKey{K: "c", Name: "charlie"})
+		})
+		if !errors.Is(err, errHook) {
+			t.Fatalf("expected the hook error, got %v", err)
+		}
+
+		if _, err := s.GetOne(ctx, litestore.Filter{Key: "k", Op: litestore.OpEq, Value: "c"}); err == nil {
+			t.Error("expected the save to be rolled back")
+		}
+	})
+
+	t.Run("context without audit info", func(t *testing.T) {
+		if _, ok := litestore.AuditInfoFrom(ctx); ok {
+			t.Error("expected no audit info on a plain context")
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package litestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BlobSize is a Predicate that compares the size in bytes of the entity's blob field,
+// e.g. BlobSize{Op: OpGT, Size: 1 << 20} for attachments larger than a megabyte.
+// An empty or missing blob has size 0. Only the comparison operators are supported,
+// not OpIn and OpNotIn.
+type BlobSize struct {
+	Op   Operator
+	Size int
+}
+
+func (BlobSize) isPredicate() {}
+
+// checkBlobField validates a field tagged with `litestore:"blob"`. prev is the blob field
+// found before it, if any, as an entity can only have one.
+func checkBlobField(field reflect.StructField, prev *reflect.StructField) error {
+	if prev != nil {
+		return fmt.Errorf("only one field can have the litestore:\"blob\" tag, but both %s and %s have it", prev.Name, field.Name)
+	}
+	if field.Type.Kind() != reflect.Slice || field.Type.Elem().Kind() != reflect.Uint8 {
+		return fmt.Errorf("field with litestore:\"blob\" tag must be a []byte, but field %s is %s", field.Name, field.Type)
+	}
+	if !field.IsExported() {
+		return fmt.Errorf("field with litestore:\"blob\" tag must be exported, but field %s is not", field.Name)
+	}
+	return nil
+}
+
+// isBlob reports whether key addresses the blob field, which is not part of the json
+// document.
+func (sc querySchema) isBlob(key string) bool {
+	top, _, _ := strings.Cut(key, ".")
+	top, _, _ = strings.Cut(top, "[")
+	return sc.blobKey != "" && top == sc.blobKey
+}
+
+// marshalWithoutBlob returns the json document of the entity in entityValue without its
+// blob field, together with the blob. The entity itself is left unchanged.
+func (s *Store[T]) marshalWithoutBlob(entityValue reflect.Value) ([]byte, []byte, error) {
+	blob := entityValue.Field(s.blobField.Index[0]).Bytes()
+
+	stripped := reflect.New(s.entityType)
+	stripped.Elem().Set(entityValue)
+	stripped.Elem().Field(s.blobField.Index[0]).SetBytes(nil)
+
+	data, err := json.Marshal(stripped.Interface())
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, blob, nil
+}
+
+// initBlobColumn adds the blob column to the store's table if it is missing.
+func (s *Store[T]) initBlobColumn(ctx context.Context) error {
+	ok, err := s.hasColumn(ctx, "blob")
+	if err != nil || ok {
+		return err
+	}
+	query := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN "blob" BLOB`, s.table())
+	_, err = s.db.ExecContext(ctx, query)
+	return err
+}
@@ -0,0 +1,194 @@
+package litestore_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+type Attachment struct {
+	ID      string `json:"id" litestore:"key"`
+	Name    string `json:"name"`
+	Content []byte `json:"content" litestore:"blob"`
+}
+
+func TestStore_BlobField(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[Attachment](ctx, db, "test_attachments")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	small := &Attachment{ID: "small", Name: "small.txt", Content: []byte("hello")}
+	large := &Attachment{ID: "large", Name: "large.bin", Content: bytes.Repeat([]byte{0, 1, 2, 255}, 1024)}
+	empty := &Attachment{ID: "empty", Name: "empty.txt"}
+	for _, a := range []*Attachment{small, large, empty} {
+		if err := s.Save(ctx, a); err != nil {
+			t.Fatalf("failed to save attachment: %v", err)
+		}
+	}
+
+	t.Run("blob is stored outside the json", func(t *testing.T) {
+		var jsonData string
+		var blob []byte
+		err := db.QueryRowContext(ctx, `SELECT json, blob FROM test_attachments WHERE key = 'large'`).Scan(&jsonData, &blob)
+		if err != nil {
+			t.Fatalf("failed to read row: %v", err)
+		}
+		if strings.Contains(jsonData, "content") {
+			t.Errorf("expected json without the blob field, got %s", jsonData)
+		}
+		if !bytes.Equal(blob, large.Content) {
+			t.Errorf("expected blob column to hold %d bytes, got %d", len(large.Content), len(blob))
+		}
+		if len(large.Content) != 4096 {
+			t.Errorf("expected the saved entity to keep its content, got %d bytes", len(large.Content))
+		}
+	})
+
+	t.Run("blob is read back", func(t *testing.T) {
+		got, err := s.GetOne(ctx, litestore.EqFilter("id", "large"))
+		if err != nil {
+			t.Fatalf("failed to get attachment: %v", err)
+		}
+		if !reflect.DeepEqual(got, *large) {
+			t.Errorf("expected %s with %d bytes, got %s with %d bytes", large.Name, len(large.Content), got.Name, len(got.Content))
+		}
+
+		many, err := s.GetMany(ctx, []string{"small", "empty"})
+		if err != nil {
+			t.Fatalf("failed to get attachments: %v", err)
+		}
+		if !bytes.Equal(many["small"].Content, small.Content) || many["empty"].Content != nil {
+			t.Errorf("unexpected attachments: %+v", many)
+		}
+
+		page, _, err := s.Paginate(ctx, nil, "", 10)
+		if err != nil {
+			t.Fatalf("failed to paginate: %v", err)
+		}
+		for _, a := range page {
+			if a.ID == "small" && !bytes.Equal(a.Content, small.Content) {
+				t.Errorf("expected paginated attachment to have its content, got %q", a.Content)
+			}
+		}
+	})
+
+	t.Run("query by blob size", func(t *testing.T) {
+		seq, err := s.Iter(ctx, &litestore.Query{
+			Predicate: litestore.BlobSize{Op: litestore.OpLT, Size: 100},
+			OrderBy:   []litestore.OrderBy{{Key: "name", Direction: litestore.OrderAsc}},
+		})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var names []string
+		for a, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			names = append(names, a.Name)
+		}
+		if want := []string{"empty.txt", "small.txt"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("expected %v, got %v", want, names)
+		}
+
+		if _, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.BlobSize{Op: litestore.OpIn, Size: 1}}); err == nil {
+			t.Error("expected an error for an unsupported operator, got nil")
+		}
+	})
+
+	t.Run("export and import keep the blob", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := s.Export(ctx, &buf); err != nil {
+			t.Fatalf("failed to export: %v", err)
+		}
+
+		dst, err := litestore.NewStore[Attachment](ctx, db, "test_attachments_copy")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := dst.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if err := dst.Import(ctx, &buf); err != nil {
+			t.Fatalf("failed to import: %v", err)
+		}
+		got, err := dst.GetOne(ctx, litestore.EqFilter("id", "large"))
+		if err != nil {
+			t.Fatalf("failed to get attachment: %v", err)
+		}
+		if !bytes.Equal(got.Content, large.Content) {
+			t.Errorf("expected imported attachment to have %d bytes, got %d", len(large.Content), len(got.Content))
+		}
+	})
+
+	t.Run("blob field cannot be addressed as a key", func(t *testing.T) {
+		if _, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.NEqFilter("content", "")}); err == nil {
+			t.Error("expected an error for a filter on the blob field, got nil")
+		}
+		if _, err := s.Iter(ctx, &litestore.Query{OrderBy: []litestore.OrderBy{{Key: "content", Direction: litestore.OrderAsc}}}); err == nil {
+			t.Error("expected an error for an order by the blob field, got nil")
+		}
+		if err := s.UpdatePaths(ctx, "small", map[string]any{"content": "changed"}); err == nil {
+			t.Error("expected an error for an update of the blob field, got nil")
+		}
+		if _, err := s.ExportFields(ctx, []string{"id", "content"}, nil); err == nil {
+			t.Error("expected an error for an export of the blob field, got nil")
+		}
+		if _, err := litestore.NewStore[Attachment](ctx, db, "test_attachments_blob_index", litestore.WithIndex("content")); err == nil {
+			t.Error("expected an error for an index on the blob field, got nil")
+		}
+	})
+
+	t.Run("blob size requires a blob field", func(t *testing.T) {
+		plain, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_no_blob")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := plain.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if _, err := plain.Iter(ctx, &litestore.Query{Predicate: litestore.BlobSize{Op: litestore.OpGT, Size: 0}}); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestNewStore_BlobFieldValidation(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type notBytes struct {
+		Content string `litestore:"blob"`
+	}
+	type twoBlobs struct {
+		A []byte `litestore:"blob"`
+		B []byte `litestore:"blob"`
+	}
+
+	if _, err := litestore.NewStore[notBytes](ctx, db, "test_blob_not_bytes"); err == nil {
+		t.Error("expected an error for a non-[]byte blob field, got nil")
+	}
+	if _, err := litestore.NewStore[twoBlobs](ctx, db, "test_blob_two"); err == nil {
+		t.Error("expected an error for two blob fields, got nil")
+	}
+}
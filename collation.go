@@ -0,0 +1,101 @@
+package litestore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// CollationUnicodeNoCase is a collation that compares strings case-insensitively
+// using Unicode case mapping, unlike SQLite's built-in NOCASE which only folds ASCII.
+// It is available on connections opened through a driver registered with RegisterDriver.
+const CollationUnicodeNoCase = "UNICODE_NOCASE"
+
+// builtinCollations are the collations SQLite provides on every connection.
+var builtinCollations = map[string]struct{}{
+	"BINARY": {},
+	"NOCASE": {},
+	"RTRIM":  {},
+}
+
+var (
+	collationsMu sync.RWMutex
+	// collations holds the custom collations installed on connections of drivers
+	// registered with RegisterDriver.
+	collations = map[string]func(a, b string) int{
+		CollationUnicodeNoCase: func(a, b string) int {
+			return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		},
+	}
+)
+
+// RegisterCollation adds a custom collation that will be installed on every new connection
+// opened through a driver registered with RegisterDriver. cmp must return a negative number,
+// zero or a positive number when a sorts before, equal to or after b.
+// The name must be a valid SQL identifier.
+func RegisterCollation(name string, cmp func(a, b string) int) error {
+	if !validIdentifierRe.MatchString(name) {
+		return fmt.Errorf("invalid collation name: %s", name)
+	}
+	collationsMu.Lock()
+	defer collationsMu.Unlock()
+	collations[strings.ToUpper(name)] = cmp
+	return nil
+}
+
+// RegisterDriver registers a database/sql driver under driverName that behaves like the
+// "sqlite3" driver, but installs CollationUnicodeNoCase, every collation added with
+// RegisterCollation and every function added with RegisterFunction on each new connection.
+// Open the database with this driver name to use custom collations with WithCollation
+// and custom functions in SQL.
+//
+// Like sql.Register, it panics if called twice with the same name, so call it once,
+// for example from an init function.
+func RegisterDriver(driverName string) {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			collationsMu.RLock()
+			defer collationsMu.RUnlock()
+			for name, cmp := range collations {
+				if err := conn.RegisterCollation(name, cmp); err != nil {
+					return err
+				}
+			}
+
+			functionsMu.RLock()
+			defer functionsMu.RUnlock()
+			for name, fn := range functions {
+				if err := conn.RegisterFunc(name, fn.impl, fn.pure); err != nil {
+					return fmt.Errorf("registering function %s: %w", name, err)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// WithCollation sets the collation used when comparing and ordering JSON fields,
+// e.g. "NOCASE" or CollationUnicodeNoCase. The name must be one of SQLite's built-in
+// collations (BINARY, NOCASE, RTRIM) or one added with RegisterCollation; custom
+// collations additionally require the database to be opened with a driver registered
+// via RegisterDriver.
+func WithCollation(name string) StoreOption {
+	return func(config *storeConfig) {
+		config.collation = strings.ToUpper(name)
+	}
+}
+
+// isKnownCollation reports whether name is a built-in or registered collation.
+func isKnownCollation(name string) bool {
+	name = strings.ToUpper(name)
+	if _, ok := builtinCollations[name]; ok {
+		return true
+	}
+	collationsMu.RLock()
+	defer collationsMu.RUnlock()
+	_, ok := collations[name]
+	return ok
+}
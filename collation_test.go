@@ -0,0 +1,196 @@
+package litestore_test
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+const collationTestDriver = "sqlite3_litestore_collation_test"
+
+func init() {
+	litestore.RegisterDriver(collationTestDriver)
+}
+
+func TestStore_WithCollation(t *testing.T) {
+	ctx := t.Context()
+
+	db, err := sql.Open(collationTestDriver, fmt.Sprintf("file:%s/test.db?_journal_mode=WAL", t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close db: %v", err)
+		}
+	}()
+
+	names := []string{"Émile", "zoë", "apple", "émile", "Banana"}
+
+	newStore := func(t *testing.T, tableName string, collation string) *litestore.Store[TestPersonWithKey] {
+		t.Helper()
+		s, err := litestore.NewStore[TestPersonWithKey](ctx, db, tableName, litestore.WithCollation(collation))
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		t.Cleanup(func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		})
+		for _, name := range names {
+			if err := s.Save(ctx, &TestPersonWithKey{Name: name}); err != nil {
+				t.Fatalf("failed to save entity: %v", err)
+			}
+		}
+		return s
+	}
+
+	queryNames := func(t *testing.T, s *litestore.Store[TestPersonWithKey], q *litestore.Query) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, q)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var result []string
+		for e, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			result = append(result, e.Name)
+		}
+		return result
+	}
+
+	t.Run("NOCASE orders ASCII case-insensitively", func(t *testing.T) {
+		s := newStore(t, "collation_nocase", "NOCASE")
+		got := queryNames(t, s, &litestore.Query{
+			Predicate: litestore.Filter{Key: "name", Op: litestore.OpIn, Value: []string{"APPLE", "banana"}},
+			OrderBy:   []litestore.OrderBy{{Key: "name", Direction: litestore.OrderAsc}},
+		})
+		expected := []string{"apple", "Banana"}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected results: got %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("unicode collation matches non-ASCII case-insensitively", func(t *testing.T) {
+		s := newStore(t, "collation_unicode", litestore.CollationUnicodeNoCase)
+		got := queryNames(t, s, &litestore.Query{
+			Predicate: litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "ÉMILE"},
+		})
+		if len(got) != 2 {
+			t.Errorf("expected both spellings of émile to match, got %v", got)
+		}
+
+		got = queryNames(t, s, &litestore.Query{
+			Predicate: litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "ZOË"},
+		})
+		if !reflect.DeepEqual(got, []string{"zoë"}) {
+			t.Errorf("expected zoë to match, got %v", got)
+		}
+	})
+
+	t.Run("custom registered collation", func(t *testing.T) {
+		// Orders strings by length, then lexically.
+		err := litestore.RegisterCollation("by_length", func(a, b string) int {
+			if len(a) != len(b) {
+				return len(a) - len(b)
+			}
+			if a < b {
+				return -1
+			}
+			if a > b {
+				return 1
+			}
+			return 0
+		})
+		if err != nil {
+			t.Fatalf("failed to register collation: %v", err)
+		}
+		// Connections opened before the registration do not have the collation.
+		db.SetMaxIdleConns(0)
+		defer db.SetMaxIdleConns(2)
+
+		s := newStore(t, "collation_custom", "by_length")
+		got := queryNames(t, s, &litestore.Query{
+			Predicate: litestore.Filter{Key: "name", Op: litestore.OpIn, Value: []string{"apple", "Banana", "zoë"}},
+			OrderBy:   []litestore.OrderBy{{Key: "name", Direction: litestore.OrderAsc}},
+		})
+		expected := []string{"zoë", "apple", "Banana"}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected results: got %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("order by with explicit collation", func(t *testing.T) {
+		s := newStore(t, "collation_order_by", "")
+		order := func(collation string) []string {
+			return queryNames(t, s, &litestore.Query{
+				Predicate: litestore.Filter{Key: "name", Op: litestore.OpIn, Value: []string{"apple", "Banana", "zoë"}},
+				OrderBy:   []litestore.OrderBy{{Key: "name", Direction: litestore.OrderAsc, Collation: collation}},
+			})
+		}
+
+		if got, expected := order(""), []string{"Banana", "apple", "zoë"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected binary order: got %v, want %v", got, expected)
+		}
+		if got, expected := order("nocase"), []string{"apple", "Banana", "zoë"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected NOCASE order: got %v, want %v", got, expected)
+		}
+
+		_, err := s.Iter(ctx, &litestore.Query{
+			OrderBy: []litestore.OrderBy{{Key: "name", Direction: litestore.OrderAsc, Collation: "NOCASE, 1"}},
+		})
+		if err == nil {
+			t.Fatal("expected an error for unknown collation, got nil")
+		}
+	})
+
+	t.Run("custom registered function", func(t *testing.T) {
+		err := litestore.RegisterFunction("double_it", func(x int64) int64 { return 2 * x }, true)
+		if err != nil {
+			t.Fatalf("failed to register function: %v", err)
+		}
+		// Connections opened before the registration do not have the function.
+		db.SetMaxIdleConns(0)
+		defer db.SetMaxIdleConns(2)
+
+		s := newStore(t, "function_custom", "")
+		if err := s.Save(ctx, &TestPersonWithKey{Name: "valued", Value: 21}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+
+		var got int64
+		err = db.QueryRowContext(ctx, "SELECT double_it(json_extract(json, '$.value')) FROM function_custom WHERE json_extract(json, '$.name') = 'valued'").Scan(&got)
+		if err != nil {
+			t.Fatalf("failed to query with custom function: %v", err)
+		}
+		if got != 42 {
+			t.Errorf("expected 42, got %d", got)
+		}
+
+		for _, name := range []string{"bad name", "1double"} {
+			if err := litestore.RegisterFunction(name, func() int { return 0 }, true); err == nil {
+				t.Errorf("expected an error for the invalid function name %q, got nil", name)
+			}
+		}
+	})
+
+	t.Run("collation name starting with a digit is rejected", func(t *testing.T) {
+		if err := litestore.RegisterCollation("1ci", strings.Compare); err == nil {
+			t.Error("expected an error for an invalid collation name, got nil")
+		}
+	})
+
+	t.Run("unknown collation is rejected", func(t *testing.T) {
+		_, err := litestore.NewStore[TestPersonWithKey](ctx, db, "collation_unknown", litestore.WithCollation("nope; DROP TABLE x"))
+		if err == nil {
+			t.Fatal("expected an error for unknown collation, got nil")
+		}
+	})
+}
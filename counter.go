@@ -0,0 +1,106 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// countersTable holds the row counts maintained for stores created with WithRowCounter.
+const countersTable = "litestore_counters"
+
+// WithRowCounter maintains the number of entities in a counter row that is updated by
+// triggers on every insert and delete, so that Store.Len is O(1) instead of a table scan.
+//
+// The cost is paid on the write side: every insert and delete also updates the shared
+// counter row, which adds a little latency to each write.
+func WithRowCounter() StoreOption {
+	return func(config *storeConfig) {
+		config.rowCounter = true
+	}
+}
+
+// Len returns the number of entities in the store. It requires the store to be created
+// with WithRowCounter.
+func (s *Store[T]) Len(ctx context.Context) (int64, error) {
+	if !s.rowCounter {
+		return 0, fmt.Errorf("row counter is not enabled for %s, create the store with WithRowCounter", s.tableName)
+	}
+
+	query := fmt.Sprintf(`SELECT "count" FROM %s WHERE "table_name" = ?`, quoteIdent(countersTable))
+	rows, err := s.queryContext(ctx, query, s.counterName())
+	if err != nil {
+		return 0, fmt.Errorf("querying row counter: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("querying row counter: %w", err)
+		}
+		return 0, fmt.Errorf("row counter for %s is missing: %w", s.tableName, sql.ErrNoRows)
+	}
+	var count int64
+	if err := rows.Scan(&count); err != nil {
+		return 0, fmt.Errorf("scanning row counter: %w", err)
+	}
+	return count, nil
+}
+
+// counterName is the name of the store's row in the counters and meta tables. Stores
+// sharing a table by record type each have their own counter and recorded configuration.
+func (s *Store[T]) counterName() string {
+	if s.recordType != "" {
+		return s.tableName + ":" + s.recordType
+	}
+	return s.tableName
+}
+
+// initRowCounter creates the counter table and the triggers that maintain the counter
+// for this store, seeding it with the current number of rows.
+func (s *Store[T]) initRowCounter(ctx context.Context) error {
+	// In a shared table, the triggers only count rows of the store's record type.
+	name, triggerPrefix := quoteLiteral(s.counterName()), s.tableName
+	var where, insertWhen, deleteWhen string
+	if scope := s.schema().scope(); scope != "" {
+		triggerPrefix = s.tableName + "_" + s.recordType
+		where = " WHERE " + scope
+		insertWhen = " WHEN NEW." + scope
+		deleteWhen = " WHEN OLD." + scope
+	}
+
+	statements := []string{
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				"table_name" TEXT PRIMARY KEY,
+				"count" INTEGER NOT NULL
+			)`, quoteIdent(countersTable)),
+		fmt.Sprintf(`
+			INSERT OR IGNORE INTO %s ("table_name", "count")
+			SELECT %s, COUNT(*) FROM %s%s`, quoteIdent(countersTable), name, s.table(), where),
+		fmt.Sprintf(`
+			CREATE TRIGGER IF NOT EXISTS %s AFTER INSERT ON %s%s
+			BEGIN
+				UPDATE %s SET "count" = "count" + 1 WHERE "table_name" = %s;
+			END`, quoteIdent(triggerPrefix+"_count_insert"), s.table(), insertWhen, quoteIdent(countersTable), name),
+		fmt.Sprintf(`
+			CREATE TRIGGER IF NOT EXISTS %s AFTER DELETE ON %s%s
+			BEGIN
+				UPDATE %s SET "count" = "count" - 1 WHERE "table_name" = %s;
+			END`, quoteIdent(triggerPrefix+"_count_delete"), s.table(), deleteWhen, quoteIdent(countersTable), name),
+	}
+
+	// Seeding and creating the triggers must happen atomically, or writes in between
+	// would be missing from the counter.
+	return WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		tx, _ := GetTx(txCtx)
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(txCtx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
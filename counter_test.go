@@ -0,0 +1,76 @@
+package litestore_test
+
+import (
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_Len(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	// Rows saved before the counter is enabled must be counted too.
+	plain, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_len")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	for _, k := range []string{"a", "b"} {
+		if err := plain.Save(ctx, &TestPersonWithKey{K: k}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+	if _, err := plain.Len(ctx); err == nil {
+		t.Error("expected an error from Len without WithRowCounter, got nil")
+	}
+	if err := plain.Close(); err != nil {
+		t.Errorf("failed to close store: %v", err)
+	}
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_len", litestore.WithRowCounter())
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	assertLen := func(t *testing.T, expected int64) {
+		t.Helper()
+		n, err := s.Len(ctx)
+		if err != nil {
+			t.Fatalf("Len failed: %v", err)
+		}
+		if n != expected {
+			t.Errorf("expected Len %d, got %d", expected, n)
+		}
+	}
+
+	assertLen(t, 2)
+
+	if err := s.Save(ctx, &TestPersonWithKey{K: "c"}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+	assertLen(t, 3)
+
+	// Updating an existing entity must not change the count.
+	if err := s.Save(ctx, &TestPersonWithKey{K: "c", Name: "updated"}); err != nil {
+		t.Fatalf("failed to update entity: %v", err)
+	}
+	assertLen(t, 3)
+
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatalf("failed to delete entity: %v", err)
+	}
+	assertLen(t, 2)
+
+	// Deleting a missing entity must not change the count either.
+	if err := s.Delete(ctx, "missing"); err != nil {
+		t.Fatalf("failed to delete entity: %v", err)
+	}
+	assertLen(t, 2)
+}
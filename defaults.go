@@ -0,0 +1,82 @@
+package litestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// defaultTagPrefix starts the litestore tag of a field with a default value, e.g.
+// `litestore:"default=user"`.
+const defaultTagPrefix = "default="
+
+// fieldDefault is the value a direct field of the entity gets when a stored document
+// does not contain it.
+type fieldDefault struct {
+	index int
+	// name is the field's JSON name, or "" if it is not part of the document.
+	name string
+	// value is the default as written in the tag.
+	value string
+}
+
+// newFieldDefault validates the default value in the litestore tag of field.
+//
+// For fields whose underlying type is string, the default is used verbatim, so
+// `litestore:"default=user"` sets "user". For every other type, it is parsed as the JSON
+// encoding of the field, e.g. `litestore:"default=10"` for an int, `litestore:"default=true"`
+// for a bool or `litestore:"default=[\"a\"]"` for a []string.
+func newFieldDefault(field reflect.StructField, jsonName string, tag string) (fieldDefault, error) {
+	if !field.IsExported() {
+		return fieldDefault{}, fmt.Errorf("field with a litestore default must be exported, but field %s is not", field.Name)
+	}
+	d := fieldDefault{index: field.Index[0], name: jsonName, value: strings.TrimPrefix(tag, defaultTagPrefix)}
+	if err := d.apply(reflect.New(field.Type).Elem()); err != nil {
+		return fieldDefault{}, fmt.Errorf("invalid default for field %s: %w", field.Name, err)
+	}
+	return d, nil
+}
+
+// apply sets v, the field's value, to the default.
+func (d fieldDefault) apply(v reflect.Value) error {
+	if v.Kind() == reflect.String {
+		v.SetString(d.value)
+		return nil
+	}
+	return json.Unmarshal([]byte(d.value), v.Addr().Interface())
+}
+
+// applyDefaults sets the fields with a default on entityValue, a struct of the entity type
+// that jsonData was unmarshaled into, if jsonData does not contain them. A field present in
+// the document keeps its stored value, even if it is null, and map or struct defaults are
+// not merged with it.
+func (s *Store[T]) applyDefaults(entityValue reflect.Value, jsonData string) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(jsonData), &doc); err != nil {
+		return err
+	}
+	for _, d := range s.defaults {
+		if d.name != "" && hasJSONKey(doc, d.name) {
+			continue
+		}
+		if err := d.apply(entityValue.Field(d.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasJSONKey reports whether doc has a key that encoding/json unmarshals into a field named
+// name, which it matches case-insensitively.
+func hasJSONKey(doc map[string]json.RawMessage, name string) bool {
+	if _, ok := doc[name]; ok {
+		return true
+	}
+	for key := range doc {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
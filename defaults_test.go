@@ -0,0 +1,148 @@
+package litestore_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_FieldDefaults(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	// The original version of the type, without the fields added later.
+	type AccountV1 struct {
+		ID   string `json:"id" litestore:"key"`
+		Name string `json:"name"`
+	}
+	type Account struct {
+		ID     string   `json:"id" litestore:"key"`
+		Name   string   `json:"name"`
+		Role   string   `json:"role" litestore:"default=user"`
+		Quota  int      `json:"quota" litestore:"default=10"`
+		Active bool     `json:"active" litestore:"default=true"`
+		Tags   []string `json:"tags" litestore:"default=[\"new\"]"`
+	}
+
+	old, err := litestore.NewStore[AccountV1](ctx, db, "test_accounts_defaults")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := old.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+	if err := old.Save(ctx, &AccountV1{ID: "a", Name: "alice"}); err != nil {
+		t.Fatalf("failed to save account: %v", err)
+	}
+
+	s, err := litestore.NewStore[Account](ctx, db, "test_accounts_defaults")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+	// Stored values, including zero values, take precedence over the defaults.
+	if err := s.Save(ctx, &Account{ID: "b", Name: "bob", Role: "admin", Tags: []string{}}); err != nil {
+		t.Fatalf("failed to save account: %v", err)
+	}
+
+	t.Run("missing fields get their default", func(t *testing.T) {
+		got, err := s.GetOne(ctx, litestore.EqFilter("id", "a"))
+		if err != nil {
+			t.Fatalf("failed to get account: %v", err)
+		}
+		want := Account{ID: "a", Name: "alice", Role: "user", Quota: 10, Active: true, Tags: []string{"new"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+
+		// Defaults are not shared between entities.
+		got.Tags[0] = "changed"
+		again, err := s.GetOne(ctx, litestore.EqFilter("id", "a"))
+		if err != nil {
+			t.Fatalf("failed to get account: %v", err)
+		}
+		if again.Tags[0] != "new" {
+			t.Errorf("expected a fresh default, got %v", again.Tags)
+		}
+	})
+
+	t.Run("stored fields are kept", func(t *testing.T) {
+		got, err := s.GetOne(ctx, litestore.EqFilter("id", "b"))
+		if err != nil {
+			t.Fatalf("failed to get account: %v", err)
+		}
+		want := Account{ID: "b", Name: "bob", Role: "admin", Quota: 0, Active: false, Tags: []string{}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("pointer entities get defaults", func(t *testing.T) {
+		ps, err := litestore.NewStore[*Account](ctx, db, "test_accounts_defaults")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := ps.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		got, err := ps.GetOne(ctx, litestore.EqFilter("id", "a"))
+		if err != nil {
+			t.Fatalf("failed to get account: %v", err)
+		}
+		if got.Role != "user" || got.Quota != 10 {
+			t.Errorf("expected defaults to be set, got %+v", got)
+		}
+	})
+
+	t.Run("stored maps and nulls are not replaced", func(t *testing.T) {
+		type Profile struct {
+			ID       string         `json:"id" litestore:"key"`
+			Settings map[string]int `json:"settings" litestore:"default={\"a\":1}"`
+			Nickname *string        `json:"nickname" litestore:"default=\"anon\""`
+		}
+		ps, err := litestore.NewStore[Profile](ctx, db, "test_profiles_defaults")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := ps.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if _, err := db.ExecContext(ctx, `INSERT INTO test_profiles_defaults (key, json) VALUES ('p', '{"id": "p", "settings": {"b": 2}, "nickname": null}')`); err != nil {
+			t.Fatalf("failed to insert profile: %v", err)
+		}
+
+		got, err := ps.GetOne(ctx, litestore.EqFilter("id", "p"))
+		if err != nil {
+			t.Fatalf("failed to get profile: %v", err)
+		}
+		if want := map[string]int{"b": 2}; !reflect.DeepEqual(got.Settings, want) {
+			t.Errorf("expected settings %v, got %v", want, got.Settings)
+		}
+		if got.Nickname != nil {
+			t.Errorf("expected the stored null nickname to be kept, got %q", *got.Nickname)
+		}
+	})
+
+	t.Run("invalid default is rejected", func(t *testing.T) {
+		type badDefault struct {
+			ID    string `json:"id" litestore:"key"`
+			Quota int    `json:"quota" litestore:"default=ten"`
+		}
+		if _, err := litestore.NewStore[badDefault](ctx, db, "test_accounts_bad_default"); err == nil {
+			t.Error("expected an error for a default that does not parse, got nil")
+		}
+	})
+}
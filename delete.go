@@ -0,0 +1,117 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// DeleteReturning deletes every entity matching p and returns the deleted entities, e.g. to
+// archive them or to emit events about them. A nil predicate deletes every entity in the
+// store. The entities are returned in no particular order, and each of them is reported to
+// the mutation hook.
+//
+// The entities are deleted and read back with a single DELETE ... RETURNING statement. It
+// runs within the transaction from ctx or the store, or else within a transaction of its
+// own, so that nothing is deleted if an entity cannot be decoded; unlike reads, deletions
+// fail on such an entity even if the store was created with WithSkipUnmarshalErrors, since
+// it would otherwise be deleted without being returned. SQLite versions before
+// 3.35 lack RETURNING; on those, the entities are selected and then deleted within the
+// same transaction instead.
+func (s *Store[T]) DeleteReturning(ctx context.Context, p Predicate) ([]T, error) {
+	if _, ok := s.txFor(ctx); ok {
+		return s.deleteReturning(ctx, p)
+	}
+
+	var deleted []T
+	err := WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		var err error
+		deleted, err = s.deleteReturning(txCtx, p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// deleteReturning implements DeleteReturning within the transaction from ctx.
+func (s *Store[T]) deleteReturning(ctx context.Context, p Predicate) ([]T, error) {
+	sc := s.schema()
+	where, args, err := (&Query{Predicate: p}).where(sc)
+	if err != nil {
+		return nil, fmt.Errorf("building query: %w", err)
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s%s RETURNING %s", s.table(), where, sc.columns())
+	rows, err := s.queryContext(ctx, query, args...)
+	if isReturningUnsupported(err) {
+		return s.selectThenDelete(ctx, where, args)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("deleting entities: %w", err)
+	}
+	deleted, keys, err := s.decodeDeleted(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.notifyDeleted(ctx, keys); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// selectThenDelete reads the entities matching where and then deletes them, for SQLite
+// versions without RETURNING. ctx must carry a transaction, so that no entity can be
+// added or changed between the two statements.
+func (s *Store[T]) selectThenDelete(ctx context.Context, where string, args []any) ([]T, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s%s", s.schema().columns(), s.table(), where)
+	rows, err := s.queryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("selecting entities to delete: %w", err)
+	}
+	deleted, keys, err := s.decodeDeleted(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.execContext(ctx, fmt.Sprintf("DELETE FROM %s%s", s.table(), where), args...); err != nil {
+		return nil, fmt.Errorf("deleting entities: %w", err)
+	}
+	if err := s.notifyDeleted(ctx, keys); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// decodeDeleted decodes every row of rows, returning the entities and their keys. A row
+// that cannot be decoded is an error, never skipped.
+func (s *Store[T]) decodeDeleted(ctx context.Context, rows *sql.Rows) ([]T, []string, error) {
+	var deleted []T
+	var keys []string
+	for pair, err := range iterRows(ctx, rows, s.decodeStrictWithKey) {
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading deleted entities: %w", err)
+		}
+		deleted = append(deleted, pair.Value)
+		keys = append(keys, pair.Key)
+	}
+	return deleted, keys, nil
+}
+
+// notifyDeleted reports the deletion of each of keys to the mutation hook.
+func (s *Store[T]) notifyDeleted(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := s.notify(ctx, Mutation{Op: MutationDelete, Key: key}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isReturningUnsupported reports whether err is the syntax error SQLite versions before
+// 3.35 return for a RETURNING clause.
+func isReturningUnsupported(err error) bool {
+	return err != nil && strings.Contains(err.Error(), `near "RETURNING": syntax error`)
+}
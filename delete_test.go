@@ -0,0 +1,165 @@
+package litestore_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_DeleteReturning(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	var deletedKeys []string
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_delete_returning",
+		litestore.WithMutationHook(func(ctx context.Context, m litestore.Mutation) error {
+			if m.Op == litestore.MutationDelete {
+				deletedKeys = append(deletedKeys, m.Key)
+			}
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, p := range []*TestPersonWithKey{
+		{K: "a", Name: "alice", Category: "old"},
+		{K: "b", Name: "bob", Category: "old"},
+		{K: "c", Name: "charlie", Category: "new"},
+		{K: "d", Name: "dave", Category: "new"},
+	} {
+		if err := s.Save(ctx, p); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	names := func(people []TestPersonWithKey) []string {
+		var got []string
+		for _, p := range people {
+			got = append(got, p.Name)
+		}
+		slices.Sort(got)
+		return got
+	}
+
+	t.Run("returns the deleted entities", func(t *testing.T) {
+		deleted, err := s.DeleteReturning(ctx, litestore.EqFilter("category", "old"))
+		if err != nil {
+			t.Fatalf("DeleteReturning failed: %v", err)
+		}
+		if got, want := names(deleted), []string{"alice", "bob"}; !slices.Equal(got, want) {
+			t.Errorf("expected %v to be deleted, got %v", want, got)
+		}
+		slices.Sort(deletedKeys)
+		if want := []string{"a", "b"}; !slices.Equal(deletedKeys, want) {
+			t.Errorf("expected mutation hook to report %v, got %v", want, deletedKeys)
+		}
+
+		_, n, err := s.Page(ctx, nil)
+		if err != nil {
+			t.Fatalf("Page failed: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("expected 2 remaining entities, got %d", n)
+		}
+	})
+
+	t.Run("no match deletes nothing", func(t *testing.T) {
+		deleted, err := s.DeleteReturning(ctx, litestore.EqFilter("category", "missing"))
+		if err != nil {
+			t.Fatalf("DeleteReturning failed: %v", err)
+		}
+		if len(deleted) != 0 {
+			t.Errorf("expected nothing to be deleted, got %v", deleted)
+		}
+	})
+
+	t.Run("rolled back with the caller's transaction", func(t *testing.T) {
+		errRollback := errors.New("rollback")
+		err := litestore.WithTransaction(ctx, db, func(txCtx context.Context) error {
+			deleted, err := s.DeleteReturning(txCtx, litestore.EqFilter("name", "charlie"))
+			if err != nil {
+				return err
+			}
+			if len(deleted) != 1 {
+				t.Errorf("expected 1 deleted entity, got %d", len(deleted))
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("expected the rollback error, got %v", err)
+		}
+		if _, err := s.GetOne(ctx, litestore.EqFilter("name", "charlie")); err != nil {
+			t.Errorf("expected charlie to survive the rollback, got %v", err)
+		}
+	})
+
+	t.Run("nil predicate deletes everything", func(t *testing.T) {
+		deleted, err := s.DeleteReturning(ctx, nil)
+		if err != nil {
+			t.Fatalf("DeleteReturning failed: %v", err)
+		}
+		if got, want := names(deleted), []string{"charlie", "dave"}; !slices.Equal(got, want) {
+			t.Errorf("expected %v to be deleted, got %v", want, got)
+		}
+	})
+
+	t.Run("invalid predicate is rejected", func(t *testing.T) {
+		if _, err := s.DeleteReturning(ctx, litestore.EqFilter("nope", 1)); err == nil {
+			t.Error("expected an error for an invalid key, got nil")
+		}
+	})
+}
+
+func TestStore_DeleteReturning_SkipUnmarshalErrors(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	var skipped []string
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_delete_returning_skip",
+		litestore.WithSkipUnmarshalErrors(func(key string, _ []byte, _ error) {
+			skipped = append(skipped, key)
+		}))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	if err := s.Save(ctx, &TestPersonWithKey{K: "a", Name: "alice"}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+	const poison = `{"name": "bob", "value": "not a number"}`
+	if _, err := db.ExecContext(ctx, `INSERT INTO test_entities_delete_returning_skip (key, json) VALUES ('b', ?)`, poison); err != nil {
+		t.Fatalf("failed to insert poison row: %v", err)
+	}
+
+	if _, err := s.DeleteReturning(ctx, nil); err == nil {
+		t.Fatal("expected an error for a row that cannot be decoded, got nil")
+	}
+	if len(skipped) != 0 {
+		t.Errorf("expected no row to be skipped, got %v", skipped)
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM test_entities_delete_returning_skip`).Scan(&n); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected nothing to be deleted, got %d rows left", n)
+	}
+}
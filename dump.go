@@ -0,0 +1,209 @@
+package litestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// importBatchSize is the number of records Import saves per transaction.
+const importBatchSize = 500
+
+// dumpRecord is a single line of the Export/Import format.
+type dumpRecord struct {
+	Key  string          `json:"key"`
+	Data json.RawMessage `json:"data"`
+	// Blob is the content of the blob column, for entities with a `litestore:"blob"` field.
+	Blob []byte `json:"blob,omitempty"`
+}
+
+// Export writes every entity in the store to w as newline-delimited JSON,
+// one {"key": ..., "data": ...} object per line, ordered by key.
+// The data is written exactly as stored, so documents that no longer match T
+// are exported as well. If T has a blob field, its content is written base64-encoded
+// in a separate "blob" property.
+func (s *Store[T]) Export(ctx context.Context, w io.Writer) error {
+	query := fmt.Sprintf(`SELECT %s FROM %s`, s.schema().columns(), s.table())
+	if scope := s.schema().scope(); scope != "" {
+		query += " WHERE " + scope
+	}
+	query += ` ORDER BY "key"`
+	rows, err := s.queryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("querying entities for export: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	enc := json.NewEncoder(w)
+	for rows.Next() {
+		var rec dumpRecord
+		var jsonData string
+		dest := []any{&rec.Key, &jsonData}
+		if s.blobField != nil {
+			dest = append(dest, &rec.Blob)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("scanning entity data row: %w", err)
+		}
+		rec.Data = json.RawMessage(jsonData)
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("writing entity with key %s: %w", rec.Key, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("during row iteration: %w", err)
+	}
+
+	return nil
+}
+
+// ExportFields returns the given fields of every entity matching p, one map per entity
+// keyed by the field names as given, e.g. to write a CSV report without unmarshaling whole
+// entities. Fields are JSON keys of the entity type and may be nested, e.g. "address.city";
+// the key field reads the primary key. A nil predicate exports every entity. Rows are
+// ordered by key.
+//
+// Values are read with json_extract, so they come back as SQLite returns them: strings,
+// int64 or float64 numbers, and booleans as 0 or 1. Objects and arrays are returned as
+// their JSON text, and a missing field is nil.
+func (s *Store[T]) ExportFields(ctx context.Context, fields []string, p Predicate) ([]map[string]any, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields to export")
+	}
+	sc := s.schema()
+	exprs := make([]string, len(fields))
+	seen := make(map[string]struct{}, len(fields))
+	for i, field := range fields {
+		if _, ok := seen[field]; ok {
+			return nil, fmt.Errorf("duplicate export field: %s", field)
+		}
+		seen[field] = struct{}{}
+		if sc.keyFieldName != "" && field == sc.keyFieldName {
+			exprs[i] = `"key"`
+			continue
+		}
+		if !sc.hasKey(field) {
+			return nil, fmt.Errorf("invalid export field: '%s' is not a valid key for this entity", field)
+		}
+		exprs[i] = sc.value(field)
+	}
+
+	where, args, err := (&Query{Predicate: p}).where(sc)
+	if err != nil {
+		return nil, fmt.Errorf("building query: %w", err)
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s%s ORDER BY "key"`, strings.Join(exprs, ", "), s.table(), where)
+	rows, err := s.queryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying fields for export: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var result []map[string]any
+	for rows.Next() {
+		values := make([]any, len(fields))
+		dest := make([]any, len(fields))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scanning exported fields: %w", err)
+		}
+		row := make(map[string]any, len(fields))
+		for i, field := range fields {
+			row[field] = values[i]
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("during row iteration: %w", err)
+	}
+	return result, nil
+}
+
+// Import reads records in the format written by Export from r and saves them
+// under their original keys, following the store's conflict policy.
+//
+// If ctx or the store carries a transaction, all records are imported within it. Otherwise
+// records are imported in batches, each in its own transaction, so a failure
+// part way through leaves the batches before it in place.
+func (s *Store[T]) Import(ctx context.Context, r io.Reader) error {
+	dec := json.NewDecoder(r)
+
+	if _, ok := s.txFor(ctx); ok {
+		_, err := s.importBatch(ctx, dec, 0)
+		return err
+	}
+
+	for {
+		var done bool
+		err := WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+			var err error
+			done, err = s.importBatch(txCtx, dec, importBatchSize)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// importBatch saves up to n records from dec, or all remaining records if n is 0.
+// It reports whether the input has been fully consumed.
+func (s *Store[T]) importBatch(ctx context.Context, dec *json.Decoder, n int) (bool, error) {
+	for i := 0; n == 0 || i < n; i++ {
+		var rec dumpRecord
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return true, nil
+			}
+			return false, fmt.Errorf("decoding import record: %w", err)
+		}
+		if rec.Key == "" {
+			return false, fmt.Errorf("import record has an empty key")
+		}
+		if len(rec.Data) == 0 {
+			return false, fmt.Errorf("import record with key %s has no data", rec.Key)
+		}
+
+		doc, external := []byte(rec.Data), []byte(nil)
+		if s.externalFields != nil {
+			var err error
+			if doc, external, err = s.splitExternal(doc); err != nil {
+				return false, fmt.Errorf("importing entity with key %s: %w", rec.Key, err)
+			}
+		}
+		if err := s.checkDocumentSize(rec.Key, doc); err != nil {
+			return false, err
+		}
+
+		args := []any{rec.Key, doc}
+		if s.blobField != nil {
+			args = append(args, rec.Blob)
+		}
+		if s.externalFields != nil {
+			args = append(args, external)
+		}
+		res, err := s.execStmt(ctx, s.saveStmt, s.saveSQL, args...)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return false, fmt.Errorf("importing entity with key %s: %w: %w", rec.Key, ErrUniqueViolation, err)
+			}
+			return false, fmt.Errorf("importing entity with key %s: %w", rec.Key, err)
+		}
+		if err := s.notifyIfChanged(ctx, res, Mutation{Op: MutationSave, Key: rec.Key}); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
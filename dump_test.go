@@ -0,0 +1,178 @@
+package litestore_test
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_ExportImport(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	src, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_export")
+	if err != nil {
+		t.Fatalf("failed to create source store: %v", err)
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	dst, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_import")
+	if err != nil {
+		t.Fatalf("failed to create destination store: %v", err)
+	}
+	defer func() {
+		if err := dst.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	var saved []TestPersonWithKey
+	for _, e := range []*TestPersonWithKey{
+		{Name: "alice", Category: "A", IsActive: true, Value: 10},
+		{Name: "bob", Category: "B", Value: 20},
+		{Name: "charlie", Category: "A", Value: 30},
+	} {
+		if err := src.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		saved = append(saved, *e)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(ctx, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(saved) {
+		t.Fatalf("expected %d exported lines, got %d:\n%s", len(saved), len(lines), buf.String())
+	}
+
+	if err := dst.Import(ctx, &buf); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	seq, err := dst.Iter(ctx, nil)
+	if err != nil {
+		t.Fatalf("Iter failed: %v", err)
+	}
+	var imported []TestPersonWithKey
+	for e, err := range seq {
+		if err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+		imported = append(imported, e)
+	}
+
+	sort.Slice(saved, func(i, j int) bool { return saved[i].K < saved[j].K })
+	sort.Slice(imported, func(i, j int) bool { return imported[i].K < imported[j].K })
+	if !reflect.DeepEqual(imported, saved) {
+		t.Errorf("imported entities do not match exported ones.\ngot:  %+v\nwant: %+v", imported, saved)
+	}
+
+	t.Run("malformed input returns error", func(t *testing.T) {
+		err := dst.Import(ctx, strings.NewReader(`{"key": "x", "data": {"name": "ok"}}`+"\nnot json\n"))
+		if err == nil {
+			t.Fatal("expected an error for malformed input, got nil")
+		}
+	})
+
+	t.Run("record without key returns error", func(t *testing.T) {
+		err := dst.Import(ctx, strings.NewReader(`{"data": {"name": "no-key"}}`))
+		if err == nil {
+			t.Fatal("expected an error for a record without key, got nil")
+		}
+	})
+}
+
+func TestStore_ExportFields(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Address struct {
+		City string `json:"city"`
+	}
+	type Customer struct {
+		ID      string   `json:"id" litestore:"key"`
+		Name    string   `json:"name"`
+		Orders  int      `json:"orders"`
+		VIP     bool     `json:"vip"`
+		Address *Address `json:"address,omitempty"`
+	}
+
+	s, err := litestore.NewStore[Customer](ctx, db, "test_customers_export_fields")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, c := range []*Customer{
+		{ID: "c1", Name: "alice", Orders: 3, VIP: true, Address: &Address{City: "Oslo"}},
+		{ID: "c2", Name: "bob", Orders: 1},
+		{ID: "c3", Name: "carol", Orders: 7, Address: &Address{City: "Rome"}},
+	} {
+		if err := s.Save(ctx, c); err != nil {
+			t.Fatalf("failed to save customer: %v", err)
+		}
+	}
+
+	t.Run("exports the requested fields ordered by key", func(t *testing.T) {
+		got, err := s.ExportFields(ctx, []string{"id", "name", "orders", "vip", "address.city"}, nil)
+		if err != nil {
+			t.Fatalf("ExportFields failed: %v", err)
+		}
+		want := []map[string]any{
+			{"id": "c1", "name": "alice", "orders": int64(3), "vip": int64(1), "address.city": "Oslo"},
+			{"id": "c2", "name": "bob", "orders": int64(1), "vip": int64(0), "address.city": nil},
+			{"id": "c3", "name": "carol", "orders": int64(7), "vip": int64(0), "address.city": "Rome"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected export:\ngot:  %v\nwant: %v", got, want)
+		}
+	})
+
+	t.Run("filters with the predicate", func(t *testing.T) {
+		got, err := s.ExportFields(ctx, []string{"name"}, litestore.GTFilter("orders", 2))
+		if err != nil {
+			t.Fatalf("ExportFields failed: %v", err)
+		}
+		want := []map[string]any{{"name": "alice"}, {"name": "carol"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected export:\ngot:  %v\nwant: %v", got, want)
+		}
+	})
+
+	t.Run("objects are returned as JSON text", func(t *testing.T) {
+		got, err := s.ExportFields(ctx, []string{"address"}, litestore.EqFilter("id", "c1"))
+		if err != nil {
+			t.Fatalf("ExportFields failed: %v", err)
+		}
+		if want := []map[string]any{{"address": `{"city":"Oslo"}`}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected export:\ngot:  %v\nwant: %v", got, want)
+		}
+	})
+
+	t.Run("invalid fields are rejected", func(t *testing.T) {
+		for _, fields := range [][]string{nil, {"nope"}, {"name", "name"}, {"address.street"}} {
+			if _, err := s.ExportFields(ctx, fields, nil); err == nil {
+				t.Errorf("expected an error for fields %q, got nil", fields)
+			}
+		}
+	})
+}
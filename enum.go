@@ -0,0 +1,54 @@
+package litestore
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// enumTagPrefix starts the litestore tag of a field restricted to a fixed set of values,
+// e.g. `litestore:"enum=active|suspended|deleted"`.
+const enumTagPrefix = "enum="
+
+// fieldEnum is the set of values a direct string field of the entity may hold.
+type fieldEnum struct {
+	index  int
+	name   string
+	values []string
+}
+
+// newFieldEnum parses the allowed values in the litestore tag of field, which must be an
+// exported field whose underlying type is string.
+func newFieldEnum(field reflect.StructField, tag string) (fieldEnum, error) {
+	if !field.IsExported() {
+		return fieldEnum{}, fmt.Errorf("field with a litestore enum must be exported, but field %s is not", field.Name)
+	}
+	if field.Type.Kind() != reflect.String {
+		return fieldEnum{}, fmt.Errorf("field with a litestore enum must be a string, but field %s is %s", field.Name, field.Type.Kind())
+	}
+	values := strings.Split(strings.TrimPrefix(tag, enumTagPrefix), "|")
+	if len(values) == 1 && values[0] == "" {
+		return fieldEnum{}, fmt.Errorf("litestore enum of field %s has no values", field.Name)
+	}
+	return fieldEnum{index: field.Index[0], name: field.Name, values: values}, nil
+}
+
+// check returns an error if v, the field's value, is not one of the allowed values.
+func (e fieldEnum) check(v reflect.Value) error {
+	if !slices.Contains(e.values, v.String()) {
+		return fmt.Errorf("field %s must be one of %s, but is %q", e.name, strings.Join(e.values, ", "), v.String())
+	}
+	return nil
+}
+
+// checkEnums returns an error if a field with an enum on entityValue, a struct of the
+// entity type, holds a value outside its set.
+func (s *Store[T]) checkEnums(entityValue reflect.Value) error {
+	for _, e := range s.enums {
+		if err := e.check(entityValue.Field(e.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package litestore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_EnumFields(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Status string
+	type Member struct {
+		ID     string `json:"id" litestore:"key"`
+		Status Status `json:"status" litestore:"enum=active|suspended|deleted"`
+		Tier   string `json:"tier" litestore:"enum=|gold"`
+	}
+
+	s, err := litestore.NewStore[Member](ctx, db, "test_members_enum", litestore.WithIndex("status"))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	t.Run("allowed values are saved", func(t *testing.T) {
+		for _, m := range []*Member{{ID: "a", Status: "active"}, {ID: "b", Status: "suspended", Tier: "gold"}} {
+			if err := s.Save(ctx, m); err != nil {
+				t.Fatalf("failed to save member: %v", err)
+			}
+		}
+		got, err := s.GetOne(ctx, litestore.EqFilter("status", "suspended"))
+		if err != nil {
+			t.Fatalf("GetOne failed: %v", err)
+		}
+		if got.ID != "b" {
+			t.Errorf("expected member b, got %+v", got)
+		}
+	})
+
+	t.Run("other values are rejected", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			member *Member
+		}{
+			{"unknown value", &Member{Status: "banned"}},
+			{"empty value not listed", &Member{}},
+			{"second enum", &Member{Status: "active", Tier: "silver"}},
+		}
+		for _, tt := range tests {
+			err := s.Save(ctx, tt.member)
+			if err == nil {
+				t.Errorf("%s: expected an error, got nil", tt.name)
+				continue
+			}
+			if !strings.Contains(err.Error(), "must be one of") {
+				t.Errorf("%s: expected an error listing the allowed values, got %v", tt.name, err)
+			}
+			if tt.member.ID != "" {
+				t.Errorf("%s: expected no key to be generated for a rejected entity, got %q", tt.name, tt.member.ID)
+			}
+		}
+		if err := s.SaveWithKey(ctx, "c", &Member{Status: "banned"}); err == nil {
+			t.Error("SaveWithKey: expected an error, got nil")
+		}
+	})
+
+	t.Run("invalid enum tags are rejected", func(t *testing.T) {
+		type notString struct {
+			ID    string `json:"id" litestore:"key"`
+			Level int    `json:"level" litestore:"enum=1|2"`
+		}
+		if _, err := litestore.NewStore[notString](ctx, db, "test_members_enum_int"); err == nil {
+			t.Error("expected an error for an enum on an int field, got nil")
+		}
+		type noValues struct {
+			ID     string `json:"id" litestore:"key"`
+			Status string `json:"status" litestore:"enum="`
+		}
+		if _, err := litestore.NewStore[noValues](ctx, db, "test_members_enum_empty"); err == nil {
+			t.Error("expected an error for an enum without values, got nil")
+		}
+	})
+}
@@ -52,7 +52,10 @@ func Example() {
 	// The table "users" will be created if it doesn't exist.
 	// For better performance on email lookups, you could add an index:
 	// userStore, err := litestore.NewStore[User](ctx, db, "users", litestore.WithIndex("email"))
-	userStore, err := litestore.NewStore[User](ctx, db, "users")
+	//
+	// Keys are generated deterministically here so that the output below can be checked.
+	// In a real application, leave out WithIDGenerator to get random UUIDs.
+	userStore, err := litestore.NewStore[User](ctx, db, "users", litestore.WithIDGenerator(litestore.DeterministicIDs(1)))
 	if err != nil {
 		log.Fatalf("failed to create user store: %v", err)
 	}
@@ -165,13 +168,11 @@ func Example() {
 	}
 	fmt.Printf("Found user '%s' after transaction.\n", bob.Name)
 
-	// Note: The output of this example is not checked because it contains
-	// non-deterministic UUIDs. The output on a typical run would look like this:
-	//
-	// Saved user 'Alice' with ID: <some-uuid>
+	// Output:
+	// Saved user 'Alice' with ID: 9f6067c4-caa7-a19a-9c89-39024892e324
 	// Saved 2 login events for Alice.
 	// Retrieved user: Alice (alice@example.com)
-	// Login events for user ID <some-uuid>:
+	// Login events for user ID 9f6067c4-caa7-a19a-9c89-39024892e324:
 	// - At 2023-10-27T10:00:00Z from 192.0.2.1
 	// - At 2023-10-27T12:30:00Z from 203.0.113.5
 	// Performing transactional save...
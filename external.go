@@ -0,0 +1,70 @@
+package litestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// externalTag marks a field that is stored in the external column instead of the json
+// document, e.g. a large opaque payload that queries never look at.
+const externalTag = "external"
+
+// checkExternalField validates a field tagged with `litestore:"external"`, whose JSON name
+// is jsonName.
+func checkExternalField(field reflect.StructField, jsonName string) error {
+	if !field.IsExported() {
+		return fmt.Errorf("field with litestore:\"external\" tag must be exported, but field %s is not", field.Name)
+	}
+	if jsonName == "" {
+		return fmt.Errorf("field with litestore:\"external\" tag must be encoded to JSON, but field %s is tagged json:\"-\"", field.Name)
+	}
+	return nil
+}
+
+// splitExternal moves the external fields out of doc, the json document of an entity, and
+// returns the remaining document together with a JSON object of the external fields.
+func (s *Store[T]) splitExternal(doc []byte) ([]byte, []byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, nil, fmt.Errorf("splitting external fields: %w", err)
+	}
+	external := make(map[string]json.RawMessage, len(s.externalFields))
+	for name := range s.externalFields {
+		if value, ok := fields[name]; ok {
+			external[name] = value
+			delete(fields, name)
+		}
+	}
+
+	doc, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("splitting external fields: %w", err)
+	}
+	externalDoc, err := json.Marshal(external)
+	if err != nil {
+		return nil, nil, fmt.Errorf("splitting external fields: %w", err)
+	}
+	return doc, externalDoc, nil
+}
+
+// isExternal reports whether key is an external field or a path below one.
+func (sc querySchema) isExternal(key string) bool {
+	top, _, _ := strings.Cut(key, ".")
+	top, _, _ = strings.Cut(top, "[")
+	_, ok := sc.external[top]
+	return ok
+}
+
+// initExternalColumn adds the external column to the store's table if it is missing.
+func (s *Store[T]) initExternalColumn(ctx context.Context) error {
+	ok, err := s.hasColumn(ctx, "external")
+	if err != nil || ok {
+		return err
+	}
+	query := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN "external" TEXT`, s.table())
+	_, err = s.db.ExecContext(ctx, query)
+	return err
+}
@@ -0,0 +1,226 @@
+package litestore_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+type Trace struct {
+	Spans []string       `json:"spans"`
+	Attrs map[string]int `json:"attrs"`
+}
+
+type Report struct {
+	ID      string `json:"id" litestore:"key"`
+	Service string `json:"service"`
+	Level   string `json:"level"`
+	Trace   *Trace `json:"trace,omitempty" litestore:"external"`
+	Raw     string `json:"raw" litestore:"external"`
+}
+
+func TestStore_ExternalFields(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[Report](ctx, db, "test_reports_external")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	big := &Report{
+		ID:      "r1",
+		Service: "api",
+		Level:   "error",
+		Trace:   &Trace{Spans: []string{"a", "b"}, Attrs: map[string]int{"depth": 2}},
+		Raw:     strings.Repeat("x", 4096),
+	}
+	small := &Report{ID: "r2", Service: "worker", Level: "info"}
+	for _, r := range []*Report{big, small} {
+		if err := s.Save(ctx, r); err != nil {
+			t.Fatalf("failed to save report: %v", err)
+		}
+	}
+
+	t.Run("external fields are stored outside the json", func(t *testing.T) {
+		var jsonData, external string
+		err := db.QueryRowContext(ctx, `SELECT "json", "external" FROM "test_reports_external" WHERE "key" = 'r1'`).Scan(&jsonData, &external)
+		if err != nil {
+			t.Fatalf("failed to read row: %v", err)
+		}
+		if strings.Contains(jsonData, "trace") || strings.Contains(jsonData, "raw") {
+			t.Errorf("expected json without the external fields, got %s", jsonData)
+		}
+		if !strings.Contains(external, `"spans":["a","b"]`) || !strings.Contains(external, `"raw":"xxx`) {
+			t.Errorf("expected external column to hold the external fields, got %.100s", external)
+		}
+	})
+
+	t.Run("external fields are read back", func(t *testing.T) {
+		got, err := s.GetOne(ctx, litestore.EqFilter("id", "r1"))
+		if err != nil {
+			t.Fatalf("failed to get report: %v", err)
+		}
+		if !reflect.DeepEqual(got, *big) {
+			t.Errorf("expected %+v, got %+v", *big, got)
+		}
+
+		many, err := s.GetMany(ctx, []string{"r1", "r2"})
+		if err != nil {
+			t.Fatalf("failed to get reports: %v", err)
+		}
+		if !reflect.DeepEqual(many["r2"], *small) || many["r1"].Raw != big.Raw {
+			t.Errorf("unexpected reports: %+v", many)
+		}
+
+		deleted, err := s.DeleteReturning(ctx, litestore.EqFilter("id", "r2"))
+		if err != nil {
+			t.Fatalf("failed to delete report: %v", err)
+		}
+		if len(deleted) != 1 || !reflect.DeepEqual(deleted[0], *small) {
+			t.Errorf("expected the deleted report to be returned whole, got %+v", deleted)
+		}
+		if err := s.Save(ctx, small); err != nil {
+			t.Fatalf("failed to save report: %v", err)
+		}
+	})
+
+	t.Run("other fields can be queried", func(t *testing.T) {
+		got, err := s.GetOne(ctx, litestore.EqFilter("level", "error"))
+		if err != nil {
+			t.Fatalf("failed to get report: %v", err)
+		}
+		if got.ID != "r1" || got.Trace == nil {
+			t.Errorf("unexpected report: %+v", got)
+		}
+	})
+
+	t.Run("external fields cannot be addressed", func(t *testing.T) {
+		if _, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.EqFilter("raw", "x")}); err == nil {
+			t.Error("expected an error filtering on an external field, got nil")
+		}
+		if _, err := s.Iter(ctx, &litestore.Query{OrderBy: []litestore.OrderBy{{Key: "trace.spans", Direction: litestore.OrderAsc}}}); err == nil {
+			t.Error("expected an error ordering by an external field, got nil")
+		}
+		if err := s.UpdatePaths(ctx, "r1", map[string]any{"raw": "y"}); err == nil {
+			t.Error("expected an error updating an external field, got nil")
+		}
+		if err := s.MergePatch(ctx, "r1", json.RawMessage(`{"trace": null}`)); err == nil {
+			t.Error("expected an error patching an external field, got nil")
+		}
+		if _, err := litestore.NewStore[Report](ctx, db, "test_reports_external", litestore.WithIndex("raw")); err == nil {
+			t.Error("expected an error indexing an external field, got nil")
+		}
+	})
+
+	t.Run("export and import keep the external fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := s.Export(ctx, &buf); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+
+		dst, err := litestore.NewStore[Report](ctx, db, "test_reports_external_import")
+		if err != nil {
+			t.Fatalf("failed to create destination store: %v", err)
+		}
+		defer func() {
+			if err := dst.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if err := dst.Import(ctx, &buf); err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+
+		got, err := dst.GetOne(ctx, litestore.EqFilter("id", "r1"))
+		if err != nil {
+			t.Fatalf("failed to get imported report: %v", err)
+		}
+		if !reflect.DeepEqual(got, *big) {
+			t.Errorf("expected %+v, got %+v", *big, got)
+		}
+
+		var jsonData string
+		err = db.QueryRowContext(ctx, `SELECT "json" FROM "test_reports_external_import" WHERE "key" = 'r1'`).Scan(&jsonData)
+		if err != nil {
+			t.Fatalf("failed to read row: %v", err)
+		}
+		if strings.Contains(jsonData, "raw") {
+			t.Errorf("expected imported json without the external fields, got %.100s", jsonData)
+		}
+	})
+}
+
+func TestStore_ExternalFields_ExistingDocuments(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type InlineReport struct {
+		ID  string `json:"id" litestore:"key"`
+		Raw string `json:"raw"`
+	}
+	inline, err := litestore.NewStore[InlineReport](ctx, db, "test_reports_inline")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	if err := inline.Save(ctx, &InlineReport{ID: "old", Raw: "payload"}); err != nil {
+		t.Fatalf("failed to save report: %v", err)
+	}
+	if err := inline.Close(); err != nil {
+		t.Errorf("failed to close store: %v", err)
+	}
+
+	s, err := litestore.NewStore[Report](ctx, db, "test_reports_inline")
+	if err != nil {
+		t.Fatalf("failed to reopen store with external fields: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	got, err := s.GetOne(ctx, litestore.EqFilter("id", "old"))
+	if err != nil {
+		t.Fatalf("failed to get report: %v", err)
+	}
+	if got.Raw != "payload" {
+		t.Errorf("expected the field saved inline to be read, got %q", got.Raw)
+	}
+}
+
+func TestStore_ExternalFields_Validation(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Unexported struct {
+		ID  string `json:"id" litestore:"key"`
+		raw string `litestore:"external"`
+	}
+	if _, err := litestore.NewStore[Unexported](ctx, db, "test_external_unexported"); err == nil {
+		t.Error("expected an error for an unexported external field, got nil")
+	}
+
+	type Skipped struct {
+		ID  string `json:"id" litestore:"key"`
+		Raw string `json:"-" litestore:"external"`
+	}
+	if _, err := litestore.NewStore[Skipped](ctx, db, "test_external_skipped"); err == nil {
+		t.Error("expected an error for an external field tagged json:\"-\", got nil")
+	}
+}
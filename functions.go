@@ -0,0 +1,36 @@
+package litestore
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// sqlFunction is a scalar function installed on connections of drivers registered with RegisterDriver.
+type sqlFunction struct {
+	impl any
+	pure bool
+}
+
+var (
+	functionsMu sync.RWMutex
+	// functions holds the custom SQL functions added with RegisterFunction.
+	functions = map[string]sqlFunction{}
+)
+
+// RegisterFunction adds a custom scalar SQL function that will be installed on every new
+// connection opened through a driver registered with RegisterDriver, e.g. a distance
+// function over stored coordinates. impl must be a Go function whose arguments and results
+// the go-sqlite3 driver can convert, returning a value and optionally an error; see
+// sqlite3.SQLiteConn.RegisterFunc. pure declares that impl always returns the same result
+// for the same arguments, which lets SQLite use it in indexes and optimize calls.
+// The name must be a valid SQL identifier.
+func RegisterFunction(name string, impl any, pure bool) error {
+	if !validIdentifierRe.MatchString(name) {
+		return fmt.Errorf("invalid function name: %s", name)
+	}
+	functionsMu.Lock()
+	defer functionsMu.Unlock()
+	functions[strings.ToLower(name)] = sqlFunction{impl: impl, pure: pure}
+	return nil
+}
@@ -0,0 +1,35 @@
+package litestore
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator returns a new unique key for an entity saved without one.
+type IDGenerator func() string
+
+// DeterministicIDs returns an IDGenerator that produces the same sequence of
+// UUID-formatted keys for the same seed. It is meant for tests, examples and
+// golden files, where random keys would make the output unpredictable; use it
+// with WithIDGenerator. The generator is safe for concurrent use.
+func DeterministicIDs(seed uint64) IDGenerator {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(seed, seed))
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var b [16]byte
+		for i := range b {
+			b[i] = byte(rng.Uint32())
+		}
+		id, err := uuid.FromBytes(b[:])
+		if err != nil {
+			// FromBytes only fails if the slice is not 16 bytes long.
+			panic(err)
+		}
+		return id.String()
+	}
+}
@@ -0,0 +1,75 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// IndexInfo describes an index on the store's table.
+type IndexInfo struct {
+	// Name is the name of the index in the database.
+	Name string
+	// SQL is the CREATE INDEX statement of the index.
+	SQL string
+	// Configured is true if the index is created by one of the store's options, such as
+	// WithIndex. An index that is not configured is usually left over from a field that
+	// was renamed or removed, and can be dropped with DropIndexByName.
+	Configured bool
+}
+
+// ListIndexes returns the indexes on the store's table, ordered by name. The primary key
+// is not included.
+//
+// When several stores share a table with WithRecordType, the indexes of the other entity
+// types are listed too and are not Configured for this store.
+func (s *Store[T]) ListIndexes(ctx context.Context) ([]IndexInfo, error) {
+	rows, err := s.queryContext(ctx,
+		"SELECT name, sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL ORDER BY name",
+		s.tableName)
+	if err != nil {
+		return nil, fmt.Errorf("listing indexes: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var indexes []IndexInfo
+	for rows.Next() {
+		var idx IndexInfo
+		if err := rows.Scan(&idx.Name, &idx.SQL); err != nil {
+			return nil, fmt.Errorf("scanning index: %w", err)
+		}
+		_, idx.Configured = s.indexNames[idx.Name]
+		indexes = append(indexes, idx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing indexes: %w", err)
+	}
+	return indexes, nil
+}
+
+// DropIndexByName drops the index called name, as reported by ListIndexes. It returns an
+// error if there is no such index on the store's table, or if the index is configured on
+// the store, since NewStore would create it again; remove the option first.
+func (s *Store[T]) DropIndexByName(ctx context.Context, name string) error {
+	if _, ok := s.indexNames[name]; ok {
+		return fmt.Errorf("index %s is configured on the store and cannot be dropped", name)
+	}
+
+	err := s.queryRow(ctx, []any{new(int)},
+		"SELECT 1 FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ? AND sql IS NOT NULL",
+		s.tableName, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("index %s not found on table %s", name, s.tableName)
+	}
+	if err != nil {
+		return fmt.Errorf("looking up index %s: %w", name, err)
+	}
+
+	if _, err := s.execContext(ctx, "DROP INDEX IF EXISTS "+quoteIdent(name)); err != nil {
+		return fmt.Errorf("dropping index %s: %w", name, err)
+	}
+	return nil
+}
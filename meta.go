@@ -0,0 +1,131 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// metaTable records the configuration each store was last created with.
+const metaTable = "litestore_meta"
+
+// WithVerifyConfig makes NewStore compare the store's configuration with the one recorded
+// when the store was last created on the database, and fail if they differ, instead of
+// recording the new configuration. It catches a store reopened with settings that silently
+// disagree with the data, e.g. without an index that queries rely on or with another
+// collation. The compared settings are the key and blob fields, the indexes, the collation
+// and the row counter. A store without a recorded configuration is recorded as usual.
+//
+// Combined with WithNoAutoCreate, the configuration is only compared and never recorded,
+// so that a store opened on a read-only replica is still checked against the recorded one.
+func WithVerifyConfig() StoreOption {
+	return func(config *storeConfig) {
+		config.verifyConfig = true
+	}
+}
+
+// storeMeta is the configuration of a store recorded in the meta table.
+type storeMeta struct {
+	KeyField   string   `json:"key_field,omitempty"`
+	BlobField  string   `json:"blob_field,omitempty"`
+	Indexes    []string `json:"indexes,omitempty"`
+	Collation  string   `json:"collation,omitempty"`
+	RowCounter bool     `json:"row_counter,omitempty"`
+}
+
+// meta returns the store's configuration as recorded in the meta table.
+func (s *Store[T]) meta() storeMeta {
+	return storeMeta{
+		KeyField:   s.keyFieldJSONName,
+		BlobField:  s.blobFieldJSONName,
+		Indexes:    slices.Sorted(maps.Keys(s.indexNames)),
+		Collation:  s.collation,
+		RowCounter: s.rowCounter,
+	}
+}
+
+// diff describes how m differs from recorded, one setting per element.
+func (m storeMeta) diff(recorded storeMeta) []string {
+	var diffs []string
+	add := func(name string, recorded, requested any) {
+		diffs = append(diffs, fmt.Sprintf("%s: recorded %v, requested %v", name, recorded, requested))
+	}
+	if m.KeyField != recorded.KeyField {
+		add("key field", recorded.KeyField, m.KeyField)
+	}
+	if m.BlobField != recorded.BlobField {
+		add("blob field", recorded.BlobField, m.BlobField)
+	}
+	if !slices.Equal(m.Indexes, recorded.Indexes) {
+		add("indexes", recorded.Indexes, m.Indexes)
+	}
+	if m.Collation != recorded.Collation {
+		add("collation", recorded.Collation, m.Collation)
+	}
+	if m.RowCounter != recorded.RowCounter {
+		add("row counter", recorded.RowCounter, m.RowCounter)
+	}
+	return diffs
+}
+
+// verifyMeta returns an error if the configuration recorded for the store differs from its
+// current one. It only reads the database, so a missing meta table means that no
+// configuration has been recorded yet.
+func (s *Store[T]) verifyMeta(ctx context.Context) error {
+	var raw string
+	query := fmt.Sprintf(`SELECT "config" FROM %s WHERE "store_name" = ?`, quoteIdent(metaTable))
+	err := s.db.QueryRowContext(ctx, `SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?`, metaTable).Scan(new(int))
+	if err == nil {
+		err = s.db.QueryRowContext(ctx, query, s.counterName()).Scan(&raw)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading recorded config: %w", err)
+	}
+	var recorded storeMeta
+	if err := json.Unmarshal([]byte(raw), &recorded); err != nil {
+		return fmt.Errorf("decoding recorded config: %w", err)
+	}
+	if diffs := s.meta().diff(recorded); len(diffs) > 0 {
+		return fmt.Errorf("config of %s differs from the recorded one: %s", s.counterName(), strings.Join(diffs, "; "))
+	}
+	return nil
+}
+
+// recordMeta records the store's current configuration in the meta table.
+func (s *Store[T]) recordMeta(ctx context.Context) error {
+	if err := s.initMetaTable(ctx); err != nil {
+		return err
+	}
+	config, err := json.Marshal(s.meta())
+	if err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s ("store_name", "config") VALUES (?, ?)
+		ON CONFLICT ("store_name") DO UPDATE SET "config" = excluded."config"`, quoteIdent(metaTable))
+	if _, err := s.db.ExecContext(ctx, query, s.counterName(), string(config)); err != nil {
+		return fmt.Errorf("recording config: %w", err)
+	}
+	return nil
+}
+
+// initMetaTable creates the meta table if it does not exist.
+func (s *Store[T]) initMetaTable(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			"store_name" TEXT PRIMARY KEY,
+			"config" TEXT NOT NULL
+		)`, quoteIdent(metaTable))
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("creating table %s: %w", metaTable, err)
+	}
+	return nil
+}
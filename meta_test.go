@@ -0,0 +1,103 @@
+package litestore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_WithVerifyConfig(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	open := func(t *testing.T, opts ...litestore.StoreOption) error {
+		t.Helper()
+		s, err := litestore.NewStore[IndexedEntity](ctx, db, "test_verify_config", opts...)
+		if err != nil {
+			return err
+		}
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+		return nil
+	}
+
+	if err := open(t, litestore.WithIndex("email"), litestore.WithRowCounter()); err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+
+	t.Run("matching config passes", func(t *testing.T) {
+		if err := open(t, litestore.WithIndex("email"), litestore.WithRowCounter(), litestore.WithVerifyConfig()); err != nil {
+			t.Errorf("expected the recorded config to match, got %v", err)
+		}
+	})
+
+	t.Run("differing config fails", func(t *testing.T) {
+		err := open(t, litestore.WithIndex("name"), litestore.WithVerifyConfig())
+		if err == nil {
+			t.Fatal("expected an error for a differing config, got nil")
+		}
+		for _, want := range []string{"indexes", "row counter"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected the error to mention %s, got %v", want, err)
+			}
+		}
+
+		// The failed store must not have changed the schema.
+		var n int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_test_verify_config_name'").Scan(&n); err != nil {
+			t.Fatalf("failed to inspect indexes: %v", err)
+		}
+		if n != 0 {
+			t.Error("expected the index of the rejected config not to be created")
+		}
+	})
+
+	t.Run("reopening without verification records the new config", func(t *testing.T) {
+		if err := open(t, litestore.WithIndex("name")); err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		if err := open(t, litestore.WithIndex("name"), litestore.WithVerifyConfig()); err != nil {
+			t.Errorf("expected the new config to be recorded, got %v", err)
+		}
+	})
+
+	t.Run("record types are recorded separately", func(t *testing.T) {
+		for _, opts := range [][]litestore.StoreOption{
+			{litestore.WithRecordType("a"), litestore.WithIndex("email")},
+			{litestore.WithRecordType("b")},
+		} {
+			s, err := litestore.NewStore[IndexedEntity](ctx, db, "test_verify_config_shared", opts...)
+			if err != nil {
+				t.Fatalf("failed to create new store: %v", err)
+			}
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}
+		s, err := litestore.NewStore[IndexedEntity](ctx, db, "test_verify_config_shared",
+			litestore.WithRecordType("b"), litestore.WithVerifyConfig())
+		if err != nil {
+			t.Fatalf("expected record type b to match its own config, got %v", err)
+		}
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	})
+
+	t.Run("verified without auto create", func(t *testing.T) {
+		if err := open(t, litestore.WithIndex("name"), litestore.WithNoAutoCreate(), litestore.WithVerifyConfig()); err != nil {
+			t.Errorf("expected the recorded config to match, got %v", err)
+		}
+		if err := open(t, litestore.WithIndex("email"), litestore.WithNoAutoCreate(), litestore.WithVerifyConfig()); err == nil {
+			t.Error("expected an error for a differing config, got nil")
+		}
+		// Nothing is recorded without auto create, so the config is unchanged.
+		if err := open(t, litestore.WithIndex("name"), litestore.WithVerifyConfig()); err != nil {
+			t.Errorf("expected the recorded config to be kept, got %v", err)
+		}
+	})
+}
@@ -1,16 +1,46 @@
 package litestore
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"math"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// sqlTimeFormat normalizes a date/time string to UTC with millisecond precision,
+// so that timestamps written in different locations compare as instants.
+const sqlTimeFormat = "strftime('%%Y-%%m-%%dT%%H:%%M:%%f', %s)"
+
 // Query encapsulates all parts of a database query.
 type Query struct {
 	Predicate Predicate
-	OrderBy   []OrderBy
-	Limit     int
+	// OrderBy sorts the results. If it is empty, the store's WithDefaultOrder is used, or
+	// rows are returned in SQLite's unspecified order if the store has none.
+	OrderBy []OrderBy
+	// RandomOrder shuffles the results, e.g. to pick a random sample of Limit entities.
+	// If OrderBy is also set, the results are shuffled only among entities that are equal
+	// by OrderBy. Random ordering cannot use an index: every matching row is read and
+	// sorted, so the cost grows with the number of matches even when Limit is small.
+	RandomOrder bool
+	// ScoreByPredicate ranks the results by relevance: the entities matching the most of
+	// the predicates combined by an Or come first, e.g. for faceted search. Each entity's
+	// score is the number of the Or's predicates it matches, or 1 for any other predicate.
+	// OrderBy, RandomOrder and the store's default order then break ties between entities
+	// with the same score. The score is computed for every matching row, so, as with
+	// RandomOrder, the cost grows with the number of matches even when Limit is small.
+	ScoreByPredicate bool
+	Limit            int
+	// IndexedBy forces SQLite to read the rows through the named index, as an escape hatch
+	// for the rare queries where the planner picks a poor plan. It must be one of the indexes
+	// created by the store's WithIndex, WithPartialIndex or WithSparseIndex options, e.g.
+	// "idx_users_email". The query fails if the index cannot be used to answer it.
+	IndexedBy string
 }
 
 // OrderDirection defines the sorting direction.
@@ -28,65 +58,505 @@ type OrderBy struct {
 	// you can use its JSON field name to sort by the primary key.
 	Key       string
 	Direction OrderDirection
+	// Collation overrides the store's collation for this ordering, e.g. "NOCASE" to sort
+	// "apple" before "Banana". It must be a built-in collation (BINARY, NOCASE, RTRIM) or
+	// one added with RegisterCollation. Empty means the store's collation is used.
+	Collation string
+}
+
+// querySchema describes the table and entity type a query is built against.
+type querySchema struct {
+	tableName string
+	// validKeys is the set of top-level JSON keys of the entity type.
+	validKeys map[string]struct{}
+	// entityType is the struct type of the entity, used to validate nested keys.
+	entityType reflect.Type
+	// keyFieldName is the JSON key name for the primary key field (empty string if no key field).
+	keyFieldName string
+	// collation is applied to comparisons and ordering on JSON fields (empty string for the default).
+	collation string
+	// recordType restricts queries to rows of one record type (empty string if the table
+	// holds a single entity type).
+	recordType string
+	// defaultOrder is used for queries without OrderBy (nil for SQLite's unspecified order).
+	defaultOrder []OrderBy
+	// blob is true if the entity type has a blob field stored in the blob column.
+	blob bool
+	// blobKey is the JSON name of the blob field, which queries cannot address (empty
+	// string if there is none).
+	blobKey string
+	// external holds the JSON names of the fields stored in the external column, which
+	// queries cannot address (nil if there are none).
+	external map[string]struct{}
+	// maxRows limits queries without a Limit (0 for no limit).
+	maxRows int
+	// indexes holds the names of the indexes created by the store, which IndexedBy may name.
+	indexes map[string]struct{}
+}
+
+// field returns the SQL expression for a JSON field, with the schema's collation applied.
+func (sc querySchema) field(key string) string {
+	if sc.collation != "" && sc.stringEncodedNumber(key) == reflect.Invalid {
+		return sc.value(key) + " COLLATE " + sc.collation
+	}
+	return sc.value(key)
+}
+
+// value returns the SQL expression for a JSON field. Numbers that the entity type encodes
+// as JSON strings, with the `json:",string"` option, are cast back to numbers so that they
+// compare and sort numerically rather than lexically.
+func (sc querySchema) value(key string) string {
+	switch sc.stringEncodedNumber(key) {
+	case reflect.Invalid:
+		return fieldExpr(key)
+	case reflect.Float32, reflect.Float64:
+		return "CAST(" + fieldExpr(key) + " AS REAL)"
+	default:
+		return "CAST(" + fieldExpr(key) + " AS INTEGER)"
+	}
+}
+
+// indexValue returns the SQL expression that WithIndex indexes a JSON field on. It is the
+// expression that filters and orders compare, so that those can use the index: the field
+// with the schema's collation applied, or for time.Time fields the normalized UTC timestamp.
+func (sc querySchema) indexValue(key string) string {
+	if sc.isTime(key) {
+		return timeExpr(key)
+	}
+	return sc.field(key)
+}
+
+// isTime reports whether the field at key is a time.Time or a pointer to one.
+func (sc querySchema) isTime(key string) bool {
+	if sc.entityType == nil {
+		return false
+	}
+	field, _ := resolveJSONPath(sc.entityType, key)
+	if field == nil {
+		return false
+	}
+	typ := field.Type
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ == reflect.TypeFor[time.Time]()
+}
+
+// stringEncodedNumber returns the kind of the numeric field at key if it has the
+// `json:",string"` option, or reflect.Invalid otherwise.
+func (sc querySchema) stringEncodedNumber(key string) reflect.Kind {
+	if sc.entityType == nil {
+		return reflect.Invalid
+	}
+	field, _ := resolveJSONPath(sc.entityType, key)
+	if field == nil {
+		return reflect.Invalid
+	}
+	_, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if !slices.Contains(strings.Split(opts, ","), "string") {
+		return reflect.Invalid
+	}
+	typ := field.Type
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch kind := typ.Kind(); kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return kind
+	default:
+		return reflect.Invalid
+	}
+}
+
+// hasKey reports whether key is a JSON key of the entity type. Nested keys (e.g. 'a.b')
+// are followed through nested struct fields; below a map, an interface or a type with
+// custom JSON marshaling the shape of the document is unknown, so any path is accepted.
+func (sc querySchema) hasKey(key string) bool {
+	if sc.isExternal(key) || sc.isBlob(key) {
+		return false
+	}
+	if sc.entityType == nil {
+		top, _, _ := strings.Cut(key, ".")
+		_, ok := sc.validKeys[top]
+		return ok
+	}
+	return hasJSONPath(sc.entityType, key)
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// hasJSONPath reports whether the dotted path, e.g. 'address.city' or 'items[0].name',
+// can exist in the JSON encoding of typ.
+func hasJSONPath(typ reflect.Type, path string) bool {
+	_, ok := resolveJSONPath(typ, path)
+	return ok
+}
+
+// resolveJSONPath follows path through typ like hasJSONPath, and also returns the struct
+// field that the path ends at. The field is nil if the path ends at an element of a slice
+// or array, or below a type whose JSON shape is not known statically.
+func resolveJSONPath(typ reflect.Type, path string) (*reflect.StructField, bool) {
+	var last *reflect.StructField
+	for segment := range strings.SplitSeq(path, ".") {
+		name, indexes := segment, 0
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			name, indexes = segment[:i], strings.Count(segment[i:], "[")
+		}
+
+		typ = derefJSONType(typ)
+		if typ == nil {
+			return nil, true
+		}
+		if typ.Kind() != reflect.Struct {
+			return nil, false
+		}
+		field, ok := jsonField(typ, name)
+		if !ok {
+			return nil, false
+		}
+		last, typ = &field, field.Type
+
+		for range indexes {
+			last = nil
+			typ = derefJSONType(typ)
+			if typ == nil {
+				return nil, true
+			}
+			if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+				return nil, false
+			}
+			typ = typ.Elem()
+		}
+	}
+	return last, true
+}
+
+// derefJSONType strips pointers from typ. It returns nil if the JSON shape of typ is not
+// known statically: maps, interfaces and types with custom JSON or text marshaling.
+func derefJSONType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Map || typ.Kind() == reflect.Interface {
+		return nil
+	}
+	ptr := reflect.PointerTo(typ)
+	if ptr.Implements(jsonMarshalerType) || ptr.Implements(textMarshalerType) {
+		return nil
+	}
+	return typ
+}
+
+// jsonField returns the direct field of the struct type typ that is encoded under the
+// JSON key name, following the same rules as NewStore.
+func jsonField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(jsonTag, ",")
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		if jsonName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// hasJSONFields reports whether encoding/json writes at least one field of the struct
+// type typ: an exported field without a "-" tag, or such a field promoted from an
+// embedded struct. Types with custom JSON or text marshaling are assumed to have fields.
+func hasJSONFields(typ reflect.Type) bool {
+	if derefJSONType(typ) == nil {
+		return true
+	}
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			// Fields of embedded structs are promoted even if the struct type is unexported.
+			if embedded.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+				if hasJSONFields(embedded) {
+					return true
+				}
+				continue
+			}
+		}
+		if field.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldExpr returns the SQL expression that extracts key from the json column.
+// The JSON path is inlined as a literal rather than bound as a parameter, because
+// SQLite can only use an expression index when the indexed expression matches exactly.
+func fieldExpr(key string) string {
+	return `json_extract("json", ` + quoteLiteral("$."+key) + ")"
+}
+
+// timeExpr returns the SQL expression that extracts key from the json column as a
+// timestamp normalized with sqlTimeFormat.
+func timeExpr(key string) string {
+	return fmt.Sprintf(sqlTimeFormat, fieldExpr(key))
+}
+
+// quoteIdent quotes an identifier such as a table name, so that names that are also
+// SQL keywords, e.g. "order", can be used.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral returns s as an SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// inlineArgs replaces the ? placeholders in query with args rendered as SQL literals.
+// It is used where SQLite does not accept bound parameters, such as in the WHERE
+// clause of a partial index.
+func inlineArgs(query string, args []any) (string, error) {
+	var b strings.Builder
+	argIdx := 0
+	inString := false
+
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			// An escaped quote ('') toggles twice, so it correctly stays inside the string.
+			inString = !inString
+			b.WriteRune(r)
+		case r == '?' && !inString:
+			if argIdx >= len(args) {
+				return "", fmt.Errorf("not enough arguments for query placeholders")
+			}
+			lit, err := sqlLiteral(args[argIdx])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(lit)
+			argIdx++
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	if argIdx != len(args) {
+		return "", fmt.Errorf("too many arguments for query placeholders")
+	}
+	return b.String(), nil
+}
+
+// sqlLiteral renders a filter value as an SQL literal, the same way the driver would bind it.
+func sqlLiteral(v any) (string, error) {
+	if v == nil {
+		return "NULL", nil
+	}
+	if b, ok := v.([]byte); ok {
+		return fmt.Sprintf("X'%X'", b), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return quoteLiteral(rv.String()), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("cannot use %v as an SQL literal", f)
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported literal value type: %T", v)
+	}
 }
 
 // build constructs the SQL query string and arguments.
 // It assumes q is not nil.
-// keyFieldName is the JSON key name for the primary key field (empty string if no key field).
-func (q *Query) build(tableName string, validKeys map[string]struct{}, keyFieldName string) (string, []any, error) {
+func (q *Query) build(sc querySchema) (string, []any, error) {
+	return q.buildSelect(sc, sc.columns())
+}
+
+// columns returns the columns selected to read entities: key and json, and blob if the
+// entity type has a blob field. The external fields, if any, are merged back into json.
+func (sc querySchema) columns() string {
+	columns := `"key", "json"`
+	if sc.external != nil {
+		columns = `"key", json_patch("json", COALESCE("external", '{}'))`
+	}
+	if sc.blob {
+		columns += `, "blob"`
+	}
+	return columns
+}
+
+// buildSelect is like build, but selects columns instead of key and json.
+func (q *Query) buildSelect(sc querySchema, columns string) (string, []any, error) {
 	var queryBuilder strings.Builder
 	args := []any{}
 
-	queryBuilder.WriteString(fmt.Sprintf("SELECT key, json FROM %s", tableName))
+	queryBuilder.WriteString(fmt.Sprintf("SELECT %s FROM %s", columns, quoteIdent(sc.tableName)))
+	if q.IndexedBy != "" {
+		if _, ok := sc.indexes[q.IndexedBy]; !ok {
+			return "", nil, fmt.Errorf("unknown index: %s is not an index created by the store", q.IndexedBy)
+		}
+		queryBuilder.WriteString(" INDEXED BY " + quoteIdent(q.IndexedBy))
+	}
 
-	if q.Predicate != nil {
-		whereClause, whereArgs, err := buildWhereClause(q.Predicate, validKeys, keyFieldName)
+	where, whereArgs, err := q.where(sc)
+	if err != nil {
+		return "", nil, err
+	}
+	queryBuilder.WriteString(where)
+	args = append(args, whereArgs...)
+
+	var orderClauses []string
+	if q.ScoreByPredicate {
+		score, scoreArgs, err := buildScore(q.Predicate, sc)
 		if err != nil {
 			return "", nil, err
 		}
-		if whereClause != "" {
-			queryBuilder.WriteString(" WHERE ")
-			queryBuilder.WriteString(whereClause)
-			args = append(args, whereArgs...)
-		}
+		orderClauses = append(orderClauses, score+" DESC")
+		args = append(args, scoreArgs...)
 	}
 
-	if len(q.OrderBy) > 0 {
-		var orderClauses []string
-		for _, o := range q.OrderBy {
+	orderBy := q.OrderBy
+	if len(orderBy) == 0 && !q.RandomOrder {
+		orderBy = sc.defaultOrder
+	}
+	if len(orderBy) > 0 {
+		for _, o := range orderBy {
 			if o.Direction != OrderAsc && o.Direction != OrderDesc {
 				return "", nil, fmt.Errorf("invalid order direction: %s", o.Direction)
 			}
+			var collate string
+			if o.Collation != "" {
+				if !isKnownCollation(o.Collation) {
+					return "", nil, fmt.Errorf("unknown collation in order by: %s", o.Collation)
+				}
+				collate = " COLLATE " + strings.ToUpper(o.Collation)
+			}
 			// Check if this is ordering by the primary key field
-			if keyFieldName != "" && o.Key == keyFieldName {
+			if sc.keyFieldName != "" && o.Key == sc.keyFieldName {
 				// Use the key column directly for better performance
-				orderClauses = append(orderClauses, fmt.Sprintf("key %s", o.Direction))
+				orderClauses = append(orderClauses, fmt.Sprintf(`"key"%s %s`, collate, o.Direction))
 			} else {
 				if strings.ContainsAny(o.Key, ";)") {
 					return "", nil, fmt.Errorf("invalid character in order by key: %s", o.Key)
 				}
-				// Only validate top-level keys. Nested keys (e.g. 'a.b') are not validated.
-				if !strings.Contains(o.Key, ".") {
-					if _, ok := validKeys[o.Key]; !ok {
-						return "", nil, fmt.Errorf("invalid order by key: '%s' is not a valid key for this entity", o.Key)
-					}
+				if !sc.hasKey(o.Key) {
+					return "", nil, fmt.Errorf("invalid order by key: '%s' is not a valid key for this entity", o.Key)
+				}
+				// time.Time fields are ordered as normalized instants, like they are
+				// filtered, unless a collation is requested.
+				expr := sc.field(o.Key)
+				switch {
+				case collate != "":
+					expr = sc.value(o.Key) + collate
+				case sc.isTime(o.Key):
+					expr = timeExpr(o.Key)
 				}
-				orderClauses = append(orderClauses, fmt.Sprintf("json_extract(json, ?) %s", o.Direction))
-				args = append(args, "$."+o.Key)
+				orderClauses = append(orderClauses, fmt.Sprintf("%s %s", expr, o.Direction))
 			}
 		}
+	}
+	if q.RandomOrder {
+		orderClauses = append(orderClauses, "RANDOM()")
+	}
+	if len(orderClauses) > 0 {
 		queryBuilder.WriteString(" ORDER BY ")
 		queryBuilder.WriteString(strings.Join(orderClauses, ", "))
 	}
 
-	if q.Limit > 0 {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = sc.maxRows
+	}
+	if limit > 0 {
 		queryBuilder.WriteString(" LIMIT ?")
-		args = append(args, q.Limit)
+		args = append(args, limit)
 	}
 
 	return queryBuilder.String(), args, nil
 }
 
+// buildScore builds the expression ScoreByPredicate orders by: the number of the
+// predicates of p, an Or, that a row matches. Any other predicate scores 1 if it matches.
+// A condition that is NULL for a row, e.g. on a missing field, counts as not matching.
+func buildScore(p Predicate, sc querySchema) (string, []any, error) {
+	if p == nil {
+		return "", nil, fmt.Errorf("ScoreByPredicate requires a predicate")
+	}
+	preds := []Predicate{p}
+	if or, ok := p.(Or); ok {
+		preds = or.Predicates
+	}
+
+	var terms []string
+	var args []any
+	for _, pred := range preds {
+		clause, clauseArgs, err := buildWhereClause(pred, sc)
+		if err != nil {
+			return "", nil, err
+		}
+		terms = append(terms, fmt.Sprintf("COALESCE((%s), 0)", clause))
+		args = append(args, clauseArgs...)
+	}
+	return "(" + strings.Join(terms, " + ") + ")", args, nil
+}
+
+// where builds the WHERE clause of q, including its leading " WHERE ", or an empty
+// string if q matches every entity.
+func (q *Query) where(sc querySchema) (string, []any, error) {
+	var conditions []string
+	var args []any
+	if q.Predicate != nil {
+		whereClause, whereArgs, err := buildWhereClause(q.Predicate, sc)
+		if err != nil {
+			return "", nil, err
+		}
+		if whereClause != "" {
+			conditions = append(conditions, whereClause)
+			args = append(args, whereArgs...)
+		}
+	}
+	if scope := sc.scope(); scope != "" {
+		if len(conditions) > 0 {
+			conditions[0] = "(" + conditions[0] + ")"
+		}
+		conditions = append(conditions, scope)
+	}
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
 // Predicate represents a part of a query's WHERE clause.
 // It's a "closed" interface, meaning only types within this package can implement it.
 type Predicate interface {
@@ -106,9 +576,33 @@ const (
 	OpLTE   Operator = "<="
 	OpIn    Operator = "IN"
 	OpNotIn Operator = "NOT IN"
+	// OpTypeIs matches entities whose field has the JSON type named by Value, one of the
+	// names SQLite's json_type returns: "null", "true", "false", "integer", "real", "text",
+	// "array" or "object". A field missing from the document has no type and matches none.
+	OpTypeIs Operator = "TYPE IS"
 )
 
+// jsonTypes holds the type names json_type returns, which OpTypeIs accepts.
+var jsonTypes = map[string]struct{}{
+	"null": {}, "true": {}, "false": {}, "integer": {}, "real": {}, "text": {}, "array": {}, "object": {},
+}
+
 // Filter is a Predicate that represents a single condition (e.g., 'level > 10').
+// The constructors EqFilter, GTFilter, InFilter and so on always produce a valid operator.
+//
+// If Value is a time.Time, the stored field is treated as an RFC 3339 timestamp (which is
+// how encoding/json writes time.Time) and both sides are compared as UTC instants at
+// millisecond precision, regardless of the location of either timestamp. The same applies
+// to OpIn and OpNotIn when every element of the slice is a time.Time. Boolean values,
+// single or in a slice, match JSON true and false. Numeric fields that are encoded as JSON
+// strings with the `json:",string"` option are compared, and ordered, as numbers; pass a
+// number as Value, not a string. Numbers of any Go type, including json.Number and uint64
+// values above math.MaxInt64, compare the same way as the equal number stored in the JSON.
+//
+// For OpIn and OpNotIn, Value must be a slice or an array. An empty one matches no entity
+// with OpIn and every entity with OpNotIn. A nil slice is an error instead, since it is more
+// likely a list that was never filled in than a deliberately empty one; InFilter and
+// NotInFilter called without values produce an empty list, not a nil one.
 type Filter struct {
 	Key   string
 	Op    Operator
@@ -118,6 +612,8 @@ type Filter struct {
 func (Filter) isPredicate() {}
 
 // And is a Predicate that joins multiple predicates with AND.
+// It must contain at least one predicate; an empty And is rejected when the query is built.
+// To match all entities, use a nil Predicate instead.
 type And struct {
 	Predicates []Predicate
 }
@@ -125,12 +621,29 @@ type And struct {
 func (And) isPredicate() {}
 
 // Or is a Predicate that joins multiple predicates with OR.
+// It must contain at least one predicate; an empty Or is rejected when the query is built.
+// An Or of OpEq filters on the same key is compiled to the equivalent OpIn filter, so
+// building one value by value is as efficient as using InFilter.
 type Or struct {
 	Predicates []Predicate
 }
 
 func (Or) isPredicate() {}
 
+// TupleIn is a Predicate matching entities whose fields at Keys, taken together, equal one
+// of the tuples in Values, e.g. to fetch a set of entities identified by an (org, user)
+// pair. It compiles to a single row value comparison,
+// (org, user) IN (VALUES (?, ?), (?, ?)), instead of an Or of Ands.
+//
+// Every tuple must have one value per key. Values are compared like the Value of an OpEq
+// Filter, except that time.Time values are not supported. An empty Values matches no entity.
+type TupleIn struct {
+	Keys   []string
+	Values [][]any
+}
+
+func (TupleIn) isPredicate() {}
+
 // Helper functions to make building queries more ergonomic.
 
 // AndPredicates combines predicates with a logical AND.
@@ -143,8 +656,74 @@ func OrPredicates(preds ...Predicate) Or {
 	return Or{Predicates: preds}
 }
 
+// EqFilter returns a Filter matching entities whose field at key equals value.
+func EqFilter(key string, value any) Filter {
+	return Filter{Key: key, Op: OpEq, Value: value}
+}
+
+// NEqFilter returns a Filter matching entities whose field at key does not equal value.
+func NEqFilter(key string, value any) Filter {
+	return Filter{Key: key, Op: OpNEq, Value: value}
+}
+
+// GTFilter returns a Filter matching entities whose field at key is greater than value.
+func GTFilter(key string, value any) Filter {
+	return Filter{Key: key, Op: OpGT, Value: value}
+}
+
+// GTEFilter returns a Filter matching entities whose field at key is greater than or equal to value.
+func GTEFilter(key string, value any) Filter {
+	return Filter{Key: key, Op: OpGTE, Value: value}
+}
+
+// LTFilter returns a Filter matching entities whose field at key is less than value.
+func LTFilter(key string, value any) Filter {
+	return Filter{Key: key, Op: OpLT, Value: value}
+}
+
+// LTEFilter returns a Filter matching entities whose field at key is less than or equal to value.
+func LTEFilter(key string, value any) Filter {
+	return Filter{Key: key, Op: OpLTE, Value: value}
+}
+
+// TypeIsFilter returns a Filter matching entities whose field at key has the JSON type typ,
+// e.g. "integer"; see OpTypeIs for the type names.
+func TypeIsFilter(key string, typ string) Filter {
+	return Filter{Key: key, Op: OpTypeIs, Value: typ}
+}
+
+// InFilter returns a Filter matching entities whose field at key equals one of values.
+// Without values, it matches no entity.
+func InFilter[V any](key string, values ...V) Filter {
+	if values == nil {
+		values = []V{}
+	}
+	return Filter{Key: key, Op: OpIn, Value: values}
+}
+
+// NotInFilter returns a Filter matching entities whose field at key equals none of values.
+// Without values, it matches every entity.
+func NotInFilter[V any](key string, values ...V) Filter {
+	if values == nil {
+		values = []V{}
+	}
+	return Filter{Key: key, Op: OpNotIn, Value: values}
+}
+
+// Eq builds a predicate that matches entities whose fields equal all the given values,
+// i.e. an AND of OpEq filters. The filters are ordered by key so that the generated SQL
+// is stable. An empty map produces an empty And, which is rejected when the query is built.
+func Eq(pairs map[string]any) Predicate {
+	keys := slices.Sorted(maps.Keys(pairs))
+	preds := make([]Predicate, len(keys))
+	for i, key := range keys {
+		preds[i] = Filter{Key: key, Op: OpEq, Value: pairs[key]}
+	}
+	return And{Predicates: preds}
+}
+
 // buildWhereClause recursively walks the predicate tree to build the SQL query.
-func buildWhereClause(p Predicate, validKeys map[string]struct{}, keyFieldName string) (string, []any, error) {
+func buildWhereClause(p Predicate, sc querySchema) (string, []any, error) {
 	switch v := p.(type) {
 	case Filter:
 		// Handle IN and NOT IN operators
@@ -158,19 +737,30 @@ func buildWhereClause(p Predicate, validKeys map[string]struct{}, keyFieldName s
 				return "", nil, fmt.Errorf("%s operator requires a slice value", v.Op)
 			}
 
-			// Handle nil slices as an error
+			// A nil slice is more likely a list that was never built than a deliberately
+			// empty one, so it is an error rather than matching nothing or everything.
 			if rv.Kind() == reflect.Slice && rv.IsNil() {
 				return "", nil, fmt.Errorf("%s predicate values cannot be nil", v.Op)
 			}
 
-			// Convert slice elements to []any
+			isKeyField := sc.keyFieldName != "" && v.Key == sc.keyFieldName
+			if !isKeyField && !sc.hasKey(v.Key) {
+				return "", nil, fmt.Errorf("invalid %s key: '%s' is not a valid key for this entity", v.Op, v.Key)
+			}
+
+			// Convert slice elements to []any, noting whether they are all timestamps
 			sliceLen := rv.Len()
 			values = make([]any, sliceLen)
+			allTimes := sliceLen > 0
 			for i := 0; i < sliceLen; i++ {
 				values[i] = rv.Index(i).Interface()
+				if _, ok := values[i].(time.Time); !ok {
+					allTimes = false
+				}
 			}
 
-			// Empty values slice returns an impossible condition (no results for IN, all results for NOT IN)
+			// An empty list matches no entity for IN and every entity for NOT IN, as in set logic.
+			// SQLite accepts "IN ()", but not every SQL dialect does, so spell the result out.
 			if len(values) == 0 {
 				if v.Op == OpIn {
 					return "1 = 0", nil, nil
@@ -187,23 +777,43 @@ func buildWhereClause(p Predicate, validKeys map[string]struct{}, keyFieldName s
 			inClause := strings.Join(placeholders, ", ")
 
 			// Check if this is a query on the primary key field
-			if keyFieldName != "" && v.Key == keyFieldName {
-				sql := fmt.Sprintf("key %s (%s)", v.Op, inClause)
+			if isKeyField {
+				sql := fmt.Sprintf(`"key" %s (%s)`, v.Op, inClause)
 				return sql, values, nil
 			}
 
-			// Validate top-level keys (skip nested keys)
-			if !strings.Contains(v.Key, ".") {
-				if _, ok := validKeys[v.Key]; !ok {
-					return "", nil, fmt.Errorf("invalid %s key: '%s' is not a valid key for this entity", v.Op, v.Key)
+			for i := range values {
+				fv, err := filterValue(values[i])
+				if err != nil {
+					return "", nil, err
+				}
+				values[i] = fv
+			}
+
+			// Timestamps are compared as normalized UTC instants, like single-value filters.
+			if allTimes {
+				for i := range placeholders {
+					placeholders[i] = fmt.Sprintf(sqlTimeFormat, "?")
 				}
+				sql := fmt.Sprintf("%s %s (%s)", timeExpr(v.Key), v.Op, strings.Join(placeholders, ", "))
+				return sql, values, nil
 			}
 
 			// JSON field extraction with IN clause
-			sql := fmt.Sprintf("json_extract(json, ?) %s (%s)", v.Op, inClause)
-			args := []any{"$." + v.Key}
-			args = append(args, values...)
-			return sql, args, nil
+			sql := fmt.Sprintf("%s %s (%s)", sc.field(v.Key), v.Op, inClause)
+			return sql, values, nil
+		}
+
+		if v.Op == OpTypeIs {
+			typ, ok := v.Value.(string)
+			if _, known := jsonTypes[typ]; !ok || !known {
+				return "", nil, fmt.Errorf("invalid %s value: %v is not a JSON type name", v.Op, v.Value)
+			}
+			if !sc.hasKey(v.Key) {
+				return "", nil, fmt.Errorf("invalid filter key: '%s' is not a valid key for this entity", v.Key)
+			}
+			sql := fmt.Sprintf(`json_type("json", %s) = ?`, quoteLiteral("$."+v.Key))
+			return sql, []any{typ}, nil
 		}
 
 		// Handle regular comparison operators
@@ -215,43 +825,168 @@ func buildWhereClause(p Predicate, validKeys map[string]struct{}, keyFieldName s
 		}
 
 		// Check if this is a query on the primary key field
-		if keyFieldName != "" && v.Key == keyFieldName {
-			sql := fmt.Sprintf("key %s ?", v.Op)
+		if sc.keyFieldName != "" && v.Key == sc.keyFieldName {
+			sql := fmt.Sprintf(`"key" %s ?`, v.Op)
 			return sql, []any{v.Value}, nil
 		}
 
-		// Only validate top-level keys. Nested keys (e.g. 'a.b') are not validated.
-		if !strings.Contains(v.Key, ".") {
-			if _, ok := validKeys[v.Key]; !ok {
-				return "", nil, fmt.Errorf("invalid filter key: '%s' is not a valid key for this entity", v.Key)
-			}
+		if !sc.hasKey(v.Key) {
+			return "", nil, fmt.Errorf("invalid filter key: '%s' is not a valid key for this entity", v.Key)
 		}
 
-		sql := fmt.Sprintf("json_extract(json, ?) %s ?", v.Op)
-		args := []any{"$." + v.Key, v.Value}
-		return sql, args, nil
+		// time.Time values marshal to RFC 3339 strings in their own location, so a plain
+		// string comparison would be wrong across time zones. Compare both sides as
+		// normalized UTC timestamps instead. The field side matches the index WithIndex
+		// creates on time.Time fields.
+		if _, ok := v.Value.(time.Time); ok {
+			sql := fmt.Sprintf("%s %s "+sqlTimeFormat, timeExpr(v.Key), v.Op, "?")
+			value, _ := filterValue(v.Value)
+			return sql, []any{value}, nil
+		}
+
+		value, err := filterValue(v.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		sql := fmt.Sprintf("%s %s ?", sc.field(v.Key), v.Op)
+		return sql, []any{value}, nil
+
+	case BlobSize:
+		switch v.Op {
+		case OpEq, OpNEq, OpGT, OpGTE, OpLT, OpLTE:
+		default:
+			return "", nil, fmt.Errorf("unsupported blob size operator: %s", v.Op)
+		}
+		if !sc.blob {
+			return "", nil, fmt.Errorf("blob size predicate requires a litestore:\"blob\" field")
+		}
+		return fmt.Sprintf(`COALESCE(length("blob"), 0) %s ?`, v.Op), []any{v.Size}, nil
+
+	case TupleIn:
+		return buildTupleIn(v, sc)
 
 	case And:
-		return joinPredicates(v.Predicates, "AND", validKeys, keyFieldName)
+		return joinPredicates(v.Predicates, "AND", sc)
 
 	case Or:
-		return joinPredicates(v.Predicates, "OR", validKeys, keyFieldName)
+		return joinPredicates(v.Predicates, "OR", sc)
 
 	default:
 		return "", nil, fmt.Errorf("unknown predicate type: %T", p)
 	}
 }
 
-func joinPredicates(preds []Predicate, joiner string, validKeys map[string]struct{}, keyFieldName string) (string, []any, error) {
+// filterValue converts a filter value to the form json_extract returns for it:
+// booleans become 0 or 1 and timestamps become UTC RFC 3339 strings, which are
+// then normalized with sqlTimeFormat. Numbers of any Go type, including json.Number,
+// are bound as int64 if they are integers that fit, and as float64 otherwise, the
+// same way SQLite parses them out of the stored JSON. Other values are bound as they are.
+func filterValue(v any) (any, error) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid json.Number filter value %q", v.String())
+		}
+		return f, nil
+	case nil:
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// SQLite integers are signed 64-bit; larger JSON integers are read back as reals.
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u), nil
+		}
+		return float64(rv.Uint()), nil
+	case reflect.Float32:
+		// Widening a float32 directly would add digits that encoding/json does not write,
+		// e.g. 0.1 would become 0.10000000149011612, so go through its shortest decimal form.
+		return strconv.ParseFloat(strconv.FormatFloat(rv.Float(), 'g', -1, 32), 64)
+	case reflect.Float64:
+		return rv.Float(), nil
+	default:
+		return v, nil
+	}
+}
+
+// buildTupleIn compiles a TupleIn predicate to a row value IN clause.
+func buildTupleIn(v TupleIn, sc querySchema) (string, []any, error) {
+	if len(v.Keys) == 0 {
+		return "", nil, fmt.Errorf("tuple IN predicate requires at least one key")
+	}
+	fields := make([]string, len(v.Keys))
+	for i, key := range v.Keys {
+		switch {
+		case sc.keyFieldName != "" && key == sc.keyFieldName:
+			fields[i] = `"key"`
+		case sc.hasKey(key):
+			fields[i] = sc.field(key)
+		default:
+			return "", nil, fmt.Errorf("invalid tuple IN key: '%s' is not a valid key for this entity", key)
+		}
+	}
+
+	if len(v.Values) == 0 {
+		return "1 = 0", nil, nil
+	}
+
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(v.Keys)), ", ") + ")"
+	rows := make([]string, len(v.Values))
+	args := make([]any, 0, len(v.Values)*len(v.Keys))
+	for i, tuple := range v.Values {
+		if len(tuple) != len(v.Keys) {
+			return "", nil, fmt.Errorf("tuple IN value %d has %d elements, expected %d", i, len(tuple), len(v.Keys))
+		}
+		for _, value := range tuple {
+			if _, ok := value.(time.Time); ok {
+				return "", nil, fmt.Errorf("tuple IN predicate does not support time.Time values")
+			}
+			fv, err := filterValue(value)
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, fv)
+		}
+		rows[i] = placeholder
+	}
+
+	sql := fmt.Sprintf("(%s) IN (VALUES %s)", strings.Join(fields, ", "), strings.Join(rows, ", "))
+	return sql, args, nil
+}
+
+func joinPredicates(preds []Predicate, joiner string, sc querySchema) (string, []any, error) {
+	// An empty list would otherwise silently match everything, which is almost never what
+	// a caller who built the list dynamically intended (and is dangerous for deletes).
 	if len(preds) == 0 {
-		return "", nil, nil
+		return "", nil, fmt.Errorf("empty %s predicate: at least one predicate is required", joiner)
+	}
+
+	if joiner == "OR" {
+		if in, ok := collapseEqualities(preds); ok {
+			return buildWhereClause(in, sc)
+		}
 	}
 
 	var clauses []string
 	var allArgs []any
 
 	for _, pred := range preds {
-		clause, args, err := buildWhereClause(pred, validKeys, keyFieldName)
+		clause, args, err := buildWhereClause(pred, sc)
 		if err != nil {
 			return "", nil, err
 		}
@@ -261,3 +996,33 @@ func joinPredicates(preds []Predicate, joiner string, validKeys map[string]struc
 
 	return fmt.Sprintf("(%s)", strings.Join(clauses, ") "+joiner+" (")), allArgs, nil
 }
+
+// collapseEqualities returns the OpIn filter equivalent to an Or of OpEq filters on the
+// same key, which SQLite evaluates with a single lookup instead of one per value. It
+// reports false if preds are anything else, or mix timestamps with other values, which
+// IN would compare differently.
+func collapseEqualities(preds []Predicate) (Filter, bool) {
+	if len(preds) < 2 {
+		return Filter{}, false
+	}
+	first, ok := preds[0].(Filter)
+	if !ok {
+		return Filter{}, false
+	}
+	values := make([]any, len(preds))
+	times := 0
+	for i, pred := range preds {
+		f, ok := pred.(Filter)
+		if !ok || f.Op != OpEq || f.Key != first.Key {
+			return Filter{}, false
+		}
+		if _, ok := f.Value.(time.Time); ok {
+			times++
+		}
+		values[i] = f.Value
+	}
+	if times != 0 && times != len(values) {
+		return Filter{}, false
+	}
+	return Filter{Key: first.Key, Op: OpIn, Value: values}, true
+}
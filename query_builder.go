@@ -0,0 +1,86 @@
+package litestore
+
+import "slices"
+
+// QueryBuilder builds a Query by chaining method calls, as an alternative to writing the
+// Query struct literal:
+//
+//	q := litestore.NewQuery().
+//		Where(litestore.EqFilter("category", "A")).
+//		And(litestore.GTFilter("value", 10)).
+//		OrderByDesc("value").
+//		Limit(10).
+//		Build()
+//
+// Methods modify and return the builder itself, so a builder should not be shared between
+// goroutines. Build can be called several times, e.g. to derive queries from a common base.
+type QueryBuilder struct {
+	q Query
+	// and holds the predicates joined with AND; it is empty if the query matches everything.
+	and []Predicate
+}
+
+// NewQuery returns an empty QueryBuilder, whose query matches every entity.
+func NewQuery() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+// Where adds p to the conditions that entities must match. It is the same as And, and
+// reads better as the first condition.
+func (b *QueryBuilder) Where(p Predicate) *QueryBuilder {
+	return b.And(p)
+}
+
+// And adds p to the conditions that entities must match.
+func (b *QueryBuilder) And(p Predicate) *QueryBuilder {
+	b.and = append(b.and, p)
+	return b
+}
+
+// Or makes the query match entities that match either all the conditions added so far,
+// or p. On an empty builder it is the same as Where.
+func (b *QueryBuilder) Or(p Predicate) *QueryBuilder {
+	if len(b.and) == 0 {
+		return b.And(p)
+	}
+	b.and = []Predicate{Or{Predicates: []Predicate{b.predicate(), p}}}
+	return b
+}
+
+// OrderByAsc sorts the results by key in ascending order, after any ordering added before.
+func (b *QueryBuilder) OrderByAsc(key string) *QueryBuilder {
+	b.q.OrderBy = append(b.q.OrderBy, OrderBy{Key: key, Direction: OrderAsc})
+	return b
+}
+
+// OrderByDesc sorts the results by key in descending order, after any ordering added before.
+func (b *QueryBuilder) OrderByDesc(key string) *QueryBuilder {
+	b.q.OrderBy = append(b.q.OrderBy, OrderBy{Key: key, Direction: OrderDesc})
+	return b
+}
+
+// Limit sets the maximum number of entities returned. Zero means no limit.
+func (b *QueryBuilder) Limit(n int) *QueryBuilder {
+	b.q.Limit = n
+	return b
+}
+
+// Build returns the query. Later changes to the builder do not affect it.
+func (b *QueryBuilder) Build() *Query {
+	q := b.q
+	q.Predicate = b.predicate()
+	q.OrderBy = slices.Clone(b.q.OrderBy)
+	return &q
+}
+
+// predicate returns the conditions added so far as a single predicate, or nil if there are none.
+func (b *QueryBuilder) predicate() Predicate {
+	switch len(b.and) {
+	case 0:
+		return nil
+	case 1:
+		return b.and[0]
+	default:
+		return And{Predicates: slices.Clone(b.and)}
+	}
+}
@@ -0,0 +1,111 @@
+package litestore_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	category := litestore.EqFilter("category", "A")
+	value := litestore.GTFilter("value", 10)
+	active := litestore.EqFilter("is_active", true)
+
+	tests := []struct {
+		name     string
+		got      *litestore.Query
+		expected *litestore.Query
+	}{
+		{
+			name:     "empty",
+			got:      litestore.NewQuery().Build(),
+			expected: &litestore.Query{},
+		},
+		{
+			name:     "single condition",
+			got:      litestore.NewQuery().Where(category).Build(),
+			expected: &litestore.Query{Predicate: category},
+		},
+		{
+			name: "conditions, order and limit",
+			got:  litestore.NewQuery().Where(category).And(value).OrderByDesc("value").OrderByAsc("name").Limit(10).Build(),
+			expected: &litestore.Query{
+				Predicate: litestore.And{Predicates: []litestore.Predicate{category, value}},
+				OrderBy: []litestore.OrderBy{
+					{Key: "value", Direction: litestore.OrderDesc},
+					{Key: "name", Direction: litestore.OrderAsc},
+				},
+				Limit: 10,
+			},
+		},
+		{
+			name: "or groups the previous conditions",
+			got:  litestore.NewQuery().Where(category).And(value).Or(active).Build(),
+			expected: &litestore.Query{
+				Predicate: litestore.Or{Predicates: []litestore.Predicate{
+					litestore.And{Predicates: []litestore.Predicate{category, value}},
+					active,
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.expected) {
+				t.Errorf("unexpected query:\ngot:  %+v\nwant: %+v", tt.got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("built queries are independent of the builder", func(t *testing.T) {
+		b := litestore.NewQuery().Where(category).OrderByAsc("name")
+		first := b.Build()
+		b.And(value).OrderByDesc("value")
+		if !reflect.DeepEqual(first, &litestore.Query{Predicate: category, OrderBy: []litestore.OrderBy{{Key: "name", Direction: litestore.OrderAsc}}}) {
+			t.Errorf("expected the first query to be unchanged, got %+v", first)
+		}
+	})
+
+	t.Run("runs against a store", func(t *testing.T) {
+		db, cleanup := setupTestDB(t)
+		defer cleanup()
+
+		s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_query_builder")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		for _, e := range []*TestPersonWithKey{
+			{Name: "alice", Category: "A", Value: 30},
+			{Name: "bob", Category: "A", Value: 5},
+			{Name: "charlie", Category: "B", Value: 50},
+		} {
+			if err := s.Save(t.Context(), e); err != nil {
+				t.Fatalf("failed to save entity: %v", err)
+			}
+		}
+
+		q := litestore.NewQuery().Where(category).Or(litestore.GTFilter("value", 40)).OrderByDesc("value").Limit(2).Build()
+		seq, err := s.Iter(t.Context(), q)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var names []string
+		for e, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			names = append(names, e.Name)
+		}
+		if want := []string{"charlie", "alice"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("expected %v, got %v", want, names)
+		}
+	})
+}
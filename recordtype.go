@@ -0,0 +1,104 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WithRecordType makes the store share its table with stores of other entity types.
+// Rows are tagged with recordType in a record_type column, and every read, write,
+// index and row counter of the store is scoped to its own record type, so several
+// Store[T] with different record types can use the same table without seeing each
+// other's entities. Keys only need to be unique within a record type.
+//
+// A table holding record types has a different schema than a table of a single type,
+// so every store using it must be created with WithRecordType. The record type must be
+// a valid SQL identifier, as it is part of the names of the indexes and triggers.
+func WithRecordType(recordType string) StoreOption {
+	return func(config *storeConfig) {
+		config.recordType = recordType
+	}
+}
+
+// scope returns the condition that restricts rows to the schema's record type,
+// or an empty string if the table holds a single entity type.
+// The record type is inlined so that SQLite can match it against partial indexes.
+func (sc querySchema) scope() string {
+	if sc.recordType == "" {
+		return ""
+	}
+	return `"record_type" = ` + quoteLiteral(sc.recordType)
+}
+
+// scoped appends the store's record type condition to cond, if the store has one.
+func (s *Store[T]) scoped(cond string) string {
+	if scope := s.schema().scope(); scope != "" {
+		return cond + " AND " + scope
+	}
+	return cond
+}
+
+// checkRecordTypeColumn returns an error if the store's table exists without a record_type
+// column, i.e. was created by a store without WithRecordType.
+func (s *Store[T]) checkRecordTypeColumn(ctx context.Context) error {
+	ok, err := s.hasColumn(ctx, "record_type")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("table %s has no record_type column, it was created without WithRecordType", s.tableName)
+	}
+	return nil
+}
+
+// hasColumn reports whether the store's table has a column with the given name.
+func (s *Store[T]) hasColumn(ctx context.Context, name string) (bool, error) {
+	var n int
+	query := "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?"
+	if err := s.db.QueryRowContext(ctx, query, s.tableName, name).Scan(&n); err != nil {
+		return false, fmt.Errorf("inspecting table %s: %w", s.tableName, err)
+	}
+	return n > 0, nil
+}
+
+// CountByRecordType returns the number of rows of each record type in tableName, a table
+// shared by stores created with WithRecordType, e.g. to see the composition of the table
+// when debugging or planning capacity. Record types without rows are absent from the result.
+// It returns an error if the table does not hold record types.
+func CountByRecordType(ctx context.Context, db *sql.DB, tableName string) (map[string]int64, error) {
+	if !validTableNameRe.MatchString(tableName) {
+		return nil, fmt.Errorf("invalid table name: %s", tableName)
+	}
+
+	var n int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = 'record_type'", tableName).Scan(&n); err != nil {
+		return nil, fmt.Errorf("inspecting table %s: %w", tableName, err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("table %s has no record_type column, it was created without WithRecordType", tableName)
+	}
+
+	query := fmt.Sprintf(`SELECT "record_type", COUNT(*) FROM %s GROUP BY "record_type"`, quoteIdent(tableName))
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("counting record types in %s: %w", tableName, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var recordType string
+		var count int64
+		if err := rows.Scan(&recordType, &count); err != nil {
+			return nil, fmt.Errorf("scanning record type count: %w", err)
+		}
+		counts[recordType] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("counting record types in %s: %w", tableName, err)
+	}
+	return counts, nil
+}
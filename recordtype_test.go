@@ -0,0 +1,230 @@
+package litestore_test
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+type TestTag struct {
+	ID    string `json:"id" litestore:"key"`
+	Label string `json:"label"`
+}
+
+func TestStore_WithRecordType(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	people, err := litestore.NewStore[TestPersonWithKey](ctx, db, "shared_entities",
+		litestore.WithRecordType("person"), litestore.WithIndex("name"), litestore.WithRowCounter())
+	if err != nil {
+		t.Fatalf("failed to create people store: %v", err)
+	}
+	defer func() {
+		if err := people.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	tags, err := litestore.NewStore[TestTag](ctx, db, "shared_entities",
+		litestore.WithRecordType("tag"), litestore.WithRowCounter())
+	if err != nil {
+		t.Fatalf("failed to create tags store: %v", err)
+	}
+	defer func() {
+		if err := tags.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	// The same key is used by both record types.
+	for _, p := range []*TestPersonWithKey{{K: "shared", Name: "alice"}, {K: "p2", Name: "bob"}} {
+		if err := people.Save(ctx, p); err != nil {
+			t.Fatalf("failed to save person: %v", err)
+		}
+	}
+	for _, tag := range []*TestTag{{ID: "shared", Label: "red"}, {ID: "t2", Label: "blue"}, {ID: "t3", Label: "green"}} {
+		if err := tags.Save(ctx, tag); err != nil {
+			t.Fatalf("failed to save tag: %v", err)
+		}
+	}
+
+	t.Run("queries only see their own record type", func(t *testing.T) {
+		seq, err := people.Iter(ctx, nil)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var names []string
+		for p, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			names = append(names, p.Name)
+		}
+		sort.Strings(names)
+		if expected := []string{"alice", "bob"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("unexpected people: got %v, want %v", names, expected)
+		}
+
+		tag, err := tags.GetOne(ctx, litestore.Filter{Key: "id", Op: litestore.OpEq, Value: "shared"})
+		if err != nil {
+			t.Fatalf("failed to get tag: %v", err)
+		}
+		if tag.Label != "red" {
+			t.Errorf("expected tag 'red' under the shared key, got %+v", tag)
+		}
+
+		got, err := tags.GetMany(ctx, []string{"shared", "p2"})
+		if err != nil {
+			t.Fatalf("GetMany failed: %v", err)
+		}
+		if len(got) != 1 || got["shared"].Label != "red" {
+			t.Errorf("expected only the tag under the shared key, got %+v", got)
+		}
+	})
+
+	t.Run("row counters are kept per record type", func(t *testing.T) {
+		n, err := people.Len(ctx)
+		if err != nil {
+			t.Fatalf("Len failed: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("expected 2 people, got %d", n)
+		}
+		n, err = tags.Len(ctx)
+		if err != nil {
+			t.Fatalf("Len failed: %v", err)
+		}
+		if n != 3 {
+			t.Errorf("expected 3 tags, got %d", n)
+		}
+	})
+
+	t.Run("writes only touch their own record type", func(t *testing.T) {
+		if err := tags.UpdatePaths(ctx, "shared", map[string]any{"label": "crimson"}); err != nil {
+			t.Fatalf("UpdatePaths failed: %v", err)
+		}
+		if err := tags.Delete(ctx, "shared"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		p, err := people.GetOne(ctx, litestore.Filter{Key: "k", Op: litestore.OpEq, Value: "shared"})
+		if err != nil {
+			t.Fatalf("expected person under the shared key to survive: %v", err)
+		}
+		if p.Name != "alice" {
+			t.Errorf("expected person to be unchanged, got %+v", p)
+		}
+
+		err = tags.Rekey(ctx, "p2", "t4")
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows when rekeying another record type's key, got %v", err)
+		}
+	})
+
+	t.Run("index covers only its record type", func(t *testing.T) {
+		query, args, err := people.CompileQuery(&litestore.Query{
+			Predicate: litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "alice"},
+		})
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		plan := queryPlan(t, db, query, args)
+		if !strings.Contains(plan, "idx_shared_entities_person_name") {
+			t.Errorf("expected query to use the record type index, got plan:\n%s", plan)
+		}
+	})
+
+	t.Run("table without record types is rejected", func(t *testing.T) {
+		plain, err := litestore.NewStore[TestTag](ctx, db, "plain_entities")
+		if err != nil {
+			t.Fatalf("failed to create plain store: %v", err)
+		}
+		if err := plain.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+
+		_, err = litestore.NewStore[TestTag](ctx, db, "plain_entities", litestore.WithRecordType("tag"))
+		if err == nil {
+			t.Fatal("expected an error for a table without a record_type column, got nil")
+		}
+	})
+
+	t.Run("invalid record type is rejected", func(t *testing.T) {
+		_, err := litestore.NewStore[TestTag](ctx, db, "shared_entities", litestore.WithRecordType("tag'; --"))
+		if err == nil {
+			t.Fatal("expected an error for an invalid record type, got nil")
+		}
+	})
+}
+
+func TestCountByRecordType(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	people, err := litestore.NewStore[TestPersonWithKey](ctx, db, "shared_counts", litestore.WithRecordType("person"))
+	if err != nil {
+		t.Fatalf("failed to create people store: %v", err)
+	}
+	defer func() {
+		if err := people.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+	tags, err := litestore.NewStore[TestTag](ctx, db, "shared_counts", litestore.WithRecordType("tag"))
+	if err != nil {
+		t.Fatalf("failed to create tags store: %v", err)
+	}
+	defer func() {
+		if err := tags.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, name := range []string{"alice", "bob", "charlie"} {
+		if err := people.Save(ctx, &TestPersonWithKey{Name: name}); err != nil {
+			t.Fatalf("failed to save person: %v", err)
+		}
+	}
+	if err := tags.Save(ctx, &TestTag{Label: "red"}); err != nil {
+		t.Fatalf("failed to save tag: %v", err)
+	}
+
+	counts, err := litestore.CountByRecordType(ctx, db, "shared_counts")
+	if err != nil {
+		t.Fatalf("CountByRecordType failed: %v", err)
+	}
+	if want := map[string]int64{"person": 3, "tag": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected %v, got %v", want, counts)
+	}
+
+	t.Run("table without record types", func(t *testing.T) {
+		s, err := litestore.NewStore[TestTag](ctx, db, "unshared_counts")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if _, err := litestore.CountByRecordType(ctx, db, "unshared_counts"); err == nil {
+			t.Error("expected an error for a table without record types, got nil")
+		}
+	})
+
+	t.Run("invalid table name", func(t *testing.T) {
+		if _, err := litestore.CountByRecordType(ctx, db, "bad; name"); err == nil {
+			t.Error("expected an error for an invalid table name, got nil")
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package litestore
+
+import (
+	"context"
+	"fmt"
+)
+
+// ResolveRefs batch-loads the entities of other that are referenced by items, avoiding
+// one query per item (the N+1 problem). getKey extracts the referenced key from an item;
+// an empty key means the item has no reference. The result maps each referenced key to
+// its entity; references to entities that do not exist are absent from it.
+//
+//	users, err := litestore.ResolveRefs(ctx, events, func(e Event) string { return e.UserID }, userStore)
+//	for _, e := range events {
+//		user, ok := users[e.UserID]
+//		...
+//	}
+func ResolveRefs[T, R any](ctx context.Context, items []T, getKey func(T) string, other *Store[R]) (map[string]R, error) {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		if key := getKey(item); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	refs, err := other.GetMany(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("resolving references: %w", err)
+	}
+	return refs, nil
+}
@@ -0,0 +1,55 @@
+package litestore_test
+
+import (
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestResolveRefs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	users, err := litestore.NewStore[User](ctx, db, "refs_users")
+	if err != nil {
+		t.Fatalf("failed to create user store: %v", err)
+	}
+	defer func() {
+		if err := users.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	alice := &User{Name: "Alice"}
+	bob := &User{Name: "Bob"}
+	for _, u := range []*User{alice, bob} {
+		if err := users.Save(ctx, u); err != nil {
+			t.Fatalf("failed to save user: %v", err)
+		}
+	}
+
+	events := []LoginEvent{
+		{UserID: alice.ID, IPAddress: "192.0.2.1"},
+		{UserID: bob.ID, IPAddress: "192.0.2.2"},
+		{UserID: alice.ID, IPAddress: "192.0.2.3"},
+		{UserID: "deleted-user", IPAddress: "192.0.2.4"},
+		{UserID: "", IPAddress: "192.0.2.5"},
+	}
+
+	refs, err := litestore.ResolveRefs(ctx, events, func(e LoginEvent) string { return e.UserID }, users)
+	if err != nil {
+		t.Fatalf("ResolveRefs failed: %v", err)
+	}
+
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 resolved users, got %d: %+v", len(refs), refs)
+	}
+	if refs[alice.ID].Name != "Alice" || refs[bob.ID].Name != "Bob" {
+		t.Errorf("unexpected resolved users: %+v", refs)
+	}
+	if _, ok := refs["deleted-user"]; ok {
+		t.Error("expected missing user to be absent from the result")
+	}
+}
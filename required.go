@@ -0,0 +1,36 @@
+package litestore
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// requiredTag marks a field that must not hold its zero value when an entity is saved,
+// e.g. an empty string or a nil pointer.
+const requiredTag = "required"
+
+// fieldRequired is a direct field of the entity that Save requires to be set.
+type fieldRequired struct {
+	index int
+	name  string
+}
+
+// newFieldRequired validates a field tagged with `litestore:"required"`, which must be
+// exported so that its value can be read.
+func newFieldRequired(field reflect.StructField) (fieldRequired, error) {
+	if !field.IsExported() {
+		return fieldRequired{}, fmt.Errorf("field with litestore:\"required\" tag must be exported, but field %s is not", field.Name)
+	}
+	return fieldRequired{index: field.Index[0], name: field.Name}, nil
+}
+
+// checkRequired returns an error naming the first required field of entityValue, a struct
+// of the entity type, that holds its zero value.
+func (s *Store[T]) checkRequired(entityValue reflect.Value) error {
+	for _, r := range s.required {
+		if entityValue.Field(r.index).IsZero() {
+			return fmt.Errorf("field %s is required, but is empty", r.name)
+		}
+	}
+	return nil
+}
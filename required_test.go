@@ -0,0 +1,86 @@
+package litestore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_RequiredFields(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Account struct {
+		ID    string  `json:"id" litestore:"key"`
+		Email string  `json:"email" litestore:"required"`
+		Owner *string `json:"owner" litestore:"required"`
+	}
+
+	s, err := litestore.NewStore[Account](ctx, db, "test_accounts_required")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	owner := "alice"
+
+	t.Run("entities with required fields set are saved", func(t *testing.T) {
+		if err := s.Save(ctx, &Account{ID: "a", Email: "a@example.com", Owner: &owner}); err != nil {
+			t.Fatalf("failed to save account: %v", err)
+		}
+		if err := s.SaveWithKey(ctx, "b", &Account{Email: "b@example.com", Owner: &owner}); err != nil {
+			t.Fatalf("failed to save account with key: %v", err)
+		}
+	})
+
+	t.Run("entities missing a required field are rejected", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			account *Account
+			field   string
+		}{
+			{"empty string", &Account{Owner: &owner}, "Email"},
+			{"nil pointer", &Account{Email: "c@example.com"}, "Owner"},
+		}
+		for _, tt := range tests {
+			err := s.Save(ctx, tt.account)
+			if err == nil {
+				t.Errorf("%s: expected an error, got nil", tt.name)
+				continue
+			}
+			if !strings.Contains(err.Error(), tt.field) {
+				t.Errorf("%s: expected the error to name field %s, got %v", tt.name, tt.field, err)
+			}
+		}
+
+		if err := s.SaveWithKey(ctx, "d", &Account{Owner: &owner}); err == nil {
+			t.Error("SaveWithKey: expected an error, got nil")
+		}
+
+		_, total, err := s.Page(ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to count accounts: %v", err)
+		}
+		if total != 2 {
+			t.Errorf("expected only the 2 valid accounts to be saved, got %d", total)
+		}
+	})
+
+	t.Run("unexported required field", func(t *testing.T) {
+		type Invalid struct {
+			ID   string `json:"id" litestore:"key"`
+			Name string `json:"name"`
+			note string `litestore:"required"`
+		}
+		if _, err := litestore.NewStore[Invalid](ctx, db, "test_invalid_required"); err == nil {
+			t.Error("expected an error for an unexported required field, got nil")
+		}
+	})
+}
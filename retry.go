@@ -0,0 +1,74 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// maxWriteRetryDelay caps the wait between two attempts of a write retried with
+// WithWriteRetry.
+const maxWriteRetryDelay = 5 * time.Second
+
+// writeRetry is the retry policy set with WithWriteRetry.
+type writeRetry struct {
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// WithWriteRetry makes Save and SaveWithKey retry a write that fails because the database
+// is busy or locked by another connection, up to maxAttempts attempts in total. The first
+// retry waits backoff, and the wait doubles after each further attempt, up to 5 seconds
+// (or backoff, if it is longer). It complements
+// SQLite's busy timeout for bursts of write contention that outlast it. backoff must be
+// positive when maxAttempts is more than 1, so that retries wait for the lock to be released
+// rather than hammer it.
+//
+// Writes within a transaction are not retried, since SQLite may require the whole
+// transaction to be rolled back; retry the transaction instead. A canceled ctx stops the
+// retries and returns the context's error.
+func WithWriteRetry(maxAttempts int, backoff time.Duration) StoreOption {
+	return func(config *storeConfig) {
+		config.writeRetry = writeRetry{maxAttempts: maxAttempts, backoff: backoff}
+	}
+}
+
+// execStmtWithRetry is like execStmt, but retries the statement as set with WithWriteRetry
+// while it fails because the database is busy.
+func (s *Store[T]) execStmtWithRetry(ctx context.Context, stmt *sql.Stmt, query string, args ...any) (sql.Result, error) {
+	if _, ok := s.txFor(ctx); ok || s.writeRetry.maxAttempts <= 1 {
+		return s.execStmt(ctx, stmt, query, args...)
+	}
+
+	delay := s.writeRetry.backoff
+	for attempt := 1; ; attempt++ {
+		res, err := s.execStmt(ctx, stmt, query, args...)
+		if err == nil || !isBusy(err) || attempt == s.writeRetry.maxAttempts {
+			return res, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if delay < maxWriteRetryDelay {
+			delay = min(delay*2, maxWriteRetryDelay)
+		}
+	}
+}
+
+// isBusy reports whether err is a SQLite error caused by another connection holding a
+// lock on the database.
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
@@ -0,0 +1,155 @@
+package litestore_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_WithWriteRetry(t *testing.T) {
+	ctx := t.Context()
+
+	// Without a busy timeout, a write fails at once while another connection holds the lock.
+	path := t.TempDir() + "/retry.db"
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=0", path))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close db: %v", err)
+		}
+	}()
+	locker, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=0", path))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := locker.Close(); err != nil {
+			t.Errorf("failed to close db: %v", err)
+		}
+	}()
+
+	var attempts atomic.Int32
+	logger := func(query string, _ []any, _ time.Duration, _ error) {
+		if strings.Contains(query, "INSERT INTO") {
+			attempts.Add(1)
+		}
+	}
+	newStore := func(t *testing.T, options ...litestore.StoreOption) *litestore.Store[TestPersonWithKey] {
+		t.Helper()
+		s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_write_retry", append(options, litestore.WithLogger(logger))...)
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		t.Cleanup(func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		})
+		return s
+	}
+
+	// lock takes the write lock on another connection and returns a function releasing it.
+	lock := func(t *testing.T) func() {
+		t.Helper()
+		conn, err := locker.Conn(ctx)
+		if err != nil {
+			t.Fatalf("failed to get connection: %v", err)
+		}
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			t.Fatalf("failed to take the write lock: %v", err)
+		}
+		var released atomic.Bool
+		return func() {
+			if released.Swap(true) {
+				return
+			}
+			if _, err := conn.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+				t.Errorf("failed to release the write lock: %v", err)
+			}
+			if err := conn.Close(); err != nil {
+				t.Errorf("failed to close connection: %v", err)
+			}
+		}
+	}
+
+	t.Run("without retry the save fails", func(t *testing.T) {
+		s := newStore(t)
+		unlock := lock(t)
+		defer unlock()
+
+		err := s.Save(ctx, &TestPersonWithKey{K: "a", Name: "alice"})
+		if err == nil || !strings.Contains(err.Error(), "database is locked") {
+			t.Fatalf("expected the save to fail while the database is locked, got %v", err)
+		}
+	})
+
+	t.Run("the save is retried until the lock is released", func(t *testing.T) {
+		s := newStore(t, litestore.WithWriteRetry(20, 5*time.Millisecond))
+		unlock := lock(t)
+		defer unlock()
+		attempts.Store(0)
+
+		timer := time.AfterFunc(30*time.Millisecond, unlock)
+		defer timer.Stop()
+
+		if err := s.Save(ctx, &TestPersonWithKey{K: "b", Name: "bob"}); err != nil {
+			t.Fatalf("expected the save to succeed after retrying, got %v", err)
+		}
+		if n := attempts.Load(); n < 2 {
+			t.Errorf("expected the save to be retried, got %d attempts", n)
+		}
+		if _, err := s.GetOne(ctx, litestore.EqFilter("k", "b")); err != nil {
+			t.Errorf("failed to get saved entity: %v", err)
+		}
+	})
+
+	t.Run("retries stop after max attempts", func(t *testing.T) {
+		s := newStore(t, litestore.WithWriteRetry(3, time.Millisecond))
+		unlock := lock(t)
+		defer unlock()
+		attempts.Store(0)
+
+		if err := s.Save(ctx, &TestPersonWithKey{K: "c", Name: "carol"}); err == nil {
+			t.Fatal("expected the save to fail while the database stays locked")
+		}
+		if n := attempts.Load(); n != 3 {
+			t.Errorf("expected 3 attempts, got %d", n)
+		}
+	})
+
+	t.Run("canceling the context stops the retries", func(t *testing.T) {
+		s := newStore(t, litestore.WithWriteRetry(100, 10*time.Millisecond))
+		unlock := lock(t)
+		defer unlock()
+
+		cancelCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if err := s.Save(cancelCtx, &TestPersonWithKey{K: "d", Name: "dave"}); err == nil {
+			t.Fatal("expected the save to fail when the context is done")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the retries to stop with the context, took %s", elapsed)
+		}
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		for _, option := range []litestore.StoreOption{
+			litestore.WithWriteRetry(-1, time.Millisecond),
+			litestore.WithWriteRetry(3, -time.Millisecond),
+			litestore.WithWriteRetry(3, 0),
+		} {
+			if _, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_write_retry", option); err == nil {
+				t.Error("expected an error for invalid write retry settings, got nil")
+			}
+		}
+	})
+}
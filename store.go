@@ -4,21 +4,55 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 var validTableNameRe = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
 
+// validIdentifierRe matches names that are interpolated into SQL without quotes, such as
+// collation and function names, which cannot start with a digit.
+var validIdentifierRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// indexNameUnsafeRe matches the characters of a field path, such as the dots of a nested
+// key, that are replaced with underscores in index names.
+var indexNameUnsafeRe = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// ErrUniqueViolation is returned when a write would create a second entity with an existing key.
+var ErrUniqueViolation = errors.New("unique constraint violation")
+
+// ErrStoreClosed is returned by the methods of a Store after Close has been called.
+var ErrStoreClosed = errors.New("store is closed")
+
+// ErrDocumentTooLarge is returned when a write would store a json document larger than
+// the limit set with WithMaxDocumentSize.
+var ErrDocumentTooLarge = errors.New("document too large")
+
+// ErrSaveAborted is returned by SaveManyFunc when its progress callback stops the load.
+var ErrSaveAborted = errors.New("save aborted")
+
+// getManyBatchSize is the maximum number of keys GetMany binds in a single query.
+const getManyBatchSize = 500
+
+// errSkipRow is returned by row decoders for rows that should be silently skipped.
+var errSkipRow = errors.New("skip row")
+
 // Store provides a key-value store for a specific entity type `T`.
-// `T` must be a struct. If it has a field tagged with `litestore:"key"`,
-// that field is used as the primary key.
+// `T` must be a struct, a pointer to a struct, or a map with string keys such as
+// map[string]any for documents without a fixed schema. If it has a field tagged with
+// `litestore:"key"`, that field is used as the primary key. The key field can be of any
+// type whose underlying type is string, such as `type UserID string`; methods that take
+// keys, like Delete and GetMany, accept plain strings, e.g. s.Delete(ctx, string(id)).
 type Store[T any] struct {
 	db        *sql.DB
 	tableName string
@@ -27,16 +61,128 @@ type Store[T any] struct {
 	// It is nil if no such field is present.
 	keyField *reflect.StructField
 
+	// keyFieldIndex caches the position of the key field, so that Save and reads avoid
+	// walking its index path on every call. The key field is always an exported, direct
+	// field of the struct.
+	keyFieldIndex int
+
 	// keyFieldJSONName holds the JSON key name for the key field.
 	// Empty string if no key field is present.
 	keyFieldJSONName string
 
+	// blobField holds information about the `litestore:"blob"` tagged field, which is
+	// stored in the blob column instead of the json document. It is nil if no such field
+	// is present. Like the key field, it is always a direct field of the struct.
+	blobField         *reflect.StructField
+	blobFieldJSONName string
+
+	// externalFields holds the JSON names of the `litestore:"external"` tagged fields, which
+	// are stored in the external column instead of the json document.
+	externalFields map[string]struct{}
+
+	// defaults holds the fields tagged with a `litestore:"default=..."` value, which is set
+	// on read when the stored document lacks the field.
+	defaults []fieldDefault
+
+	// enums holds the fields tagged with a `litestore:"enum=..."` set of values, which Save
+	// checks before writing.
+	enums []fieldEnum
+
+	// required holds the fields tagged with `litestore:"required"`, which Save rejects
+	// entities for leaving at their zero value.
+	required []fieldRequired
+
 	// validJSONKeys holds the set of JSON keys for type T.
 	validJSONKeys map[string]struct{}
 
-	// Prepared statements
+	// entityType is the struct type of T, with the pointer removed if T is a pointer.
+	entityType reflect.Type
+
+	// isPointer is true if T is a pointer to a struct rather than a struct.
+	isPointer bool
+
+	// populateKey is true if reads should set the key field from the row key.
+	populateKey bool
+
+	// newID generates keys for entities saved without one.
+	newID IDGenerator
+
+	// conflictPolicy controls what Save does when the key already exists.
+	conflictPolicy ConflictPolicy
+
+	// collation is applied to comparisons and ordering on JSON fields.
+	// Empty string if the SQLite default (BINARY) is used.
+	collation string
+
+	// logger receives every query the store runs. It is nil if no logger is configured.
+	logger QueryLogger
+
+	// onUnmarshalError is called for rows that cannot be unmarshaled into T, which are then skipped.
+	// It is nil if such rows should fail the read instead.
+	onUnmarshalError UnmarshalErrorHandler
+
+	// strictDecoding makes reads fail on documents with fields that T does not have.
+	strictDecoding bool
+
+	// maxDocumentSize is the largest json document, in bytes, that writes may store.
+	// It is zero if there is no limit.
+	maxDocumentSize int
+
+	// writeRetry is how Save retries writes that fail because the database is busy.
+	writeRetry writeRetry
+
+	// rowCounter is true if the number of rows is maintained by triggers for Len.
+	rowCounter bool
+
+	// validateJSON is true if Save checks documents with SQLite's JSON parser before storing them.
+	validateJSON bool
+
+	// recordType tags and scopes the store's rows in a table shared with other entity types.
+	// Empty string if the table holds only this store's entities.
+	recordType string
+
+	// schemaVersion is stamped on every saved row. It is 0 if schema versions are not enabled.
+	schemaVersion int
+
+	// mutationHook is called after every change to a single entity. It is nil if no hook is configured.
+	mutationHook MutationHook
+
+	// defaultOrder is applied to queries that specify no OrderBy. It is nil if such queries
+	// return rows in SQLite's unspecified order.
+	defaultOrder []OrderBy
+
+	// maxScanRows is the limit of queries that specify no Limit. It is 0 if they are unbounded.
+	maxScanRows int
+
+	// indexNames holds the names of the indexes configured with the store's options.
+	indexNames map[string]struct{}
+
+	// tx is the transaction a view created by WithinTx runs in. It is nil for stores
+	// returned by NewStore.
+	tx *sql.Tx
+
+	// closer tracks whether the store is closed. It is shared with the views created by WithinTx.
+	closer *storeCloser
+
+	// Prepared statements and the SQL they were prepared from
 	saveStmt   *sql.Stmt
+	saveSQL    string
 	deleteStmt *sql.Stmt
+	deleteSQL  string
+}
+
+// storeCloser guards the prepared statements of a store against being closed while in use.
+type storeCloser struct {
+	// mu is held for reading while a prepared statement runs, and for writing by Close.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// isClosed reports whether Close has been called.
+func (c *storeCloser) isClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.closed
 }
 
 // StoreOption defines a configuration option for Store creation.
@@ -44,49 +190,342 @@ type StoreOption func(*storeConfig)
 
 // storeConfig holds configuration options for Store creation.
 type storeConfig struct {
-	indexFields []string
+	indexes          []indexSpec
+	conflictPolicy   ConflictPolicy
+	logger           QueryLogger
+	collation        string
+	onUnmarshalError UnmarshalErrorHandler
+	rowCounter       bool
+	noAutoCreate     bool
+	noKeyPopulation  bool
+	idGenerator      IDGenerator
+	recordType       string
+	validateJSON     bool
+	mutationHook     MutationHook
+	schemaVersion    int
+	defaultOrder     []OrderBy
+	maxScanRows      int
+	strictDecoding   bool
+	verifyConfig     bool
+	maxDocumentSize  int
+	writeRetry       writeRetry
+}
+
+// QueryLogger is called after each query a Store runs, with the SQL text, its arguments,
+// how long it took and the error it returned, if any. For Iter, the duration covers
+// running the query, not consuming the iterator.
+type QueryLogger func(query string, args []any, duration time.Duration, err error)
+
+// UnmarshalErrorHandler is called with the key, raw json and error of a stored document
+// that cannot be unmarshaled into the entity type.
+type UnmarshalErrorHandler func(key string, raw []byte, err error)
+
+// ConflictPolicy defines how Save behaves when an entity with the same key already exists.
+type ConflictPolicy int
+
+const (
+	// ConflictUpsert replaces the existing entity. This is the default.
+	ConflictUpsert ConflictPolicy = iota
+	// ConflictFail makes Save return ErrUniqueViolation.
+	ConflictFail
+	// ConflictIgnore keeps the existing entity and silently discards the new one.
+	ConflictIgnore
+)
+
+// indexSpec describes an index on a JSON field.
+type indexSpec struct {
+	field string
+	// where restricts a partial index to matching rows. It is nil for a full index.
+	where Predicate
+	// sparse restricts the index to rows where the field is present and not null.
+	sparse bool
 }
 
 // WithIndex adds a JSON field to be indexed for improved query performance.
-// Multiple WithIndex options can be specified to index multiple fields.
+// Multiple WithIndex options can be specified to index multiple fields. The field may be
+// a nested key, e.g. "address.city", which is indexed as idx_<table>_address_city.
+// time.Time fields are indexed on their normalized UTC timestamp, which filters on
+// time.Time values and orders by the field compare.
 func WithIndex(fieldName string) StoreOption {
 	return func(config *storeConfig) {
-		config.indexFields = append(config.indexFields, fieldName)
+		config.indexes = append(config.indexes, indexSpec{field: fieldName})
+	}
+}
+
+// WithPartialIndex adds an index on a JSON field that only covers rows matching where,
+// e.g. only the rows whose status is not "archived". This keeps the index small when
+// queries only ever target a subset of the rows.
+//
+// The index is named idx_<table>_<field>_partial. SQLite only uses a partial index for a
+// query if it can prove from the query's WHERE clause that the index condition holds;
+// because filter values are bound as parameters, this is in practice limited to
+// conditions like "field IS NOT NULL" that are implied by any comparison on the field.
+// As with WithIndex, an existing index is not recreated if the condition changes.
+func WithPartialIndex(fieldName string, where Predicate) StoreOption {
+	return func(config *storeConfig) {
+		config.indexes = append(config.indexes, indexSpec{field: fieldName, where: where})
+	}
+}
+
+// WithSparseIndex adds an index on an optional JSON field that only covers the rows where
+// the field is present and not null. When most rows lack the field, the index is much
+// smaller than one created with WithIndex. It is still used by any Filter on the field,
+// since every comparison implies that the field is not null, but not to order a query
+// that does not also filter on the field. The index is named idx_<table>_<field>_sparse.
+// Only fields that are left out of the document when unset, e.g. with the omitempty json
+// option or as a nil pointer, are absent; an empty string or zero value is indexed.
+func WithSparseIndex(fieldName string) StoreOption {
+	return func(config *storeConfig) {
+		config.indexes = append(config.indexes, indexSpec{field: fieldName, sparse: true})
+	}
+}
+
+// WithConflictPolicy sets what Save does when an entity with the same key already exists.
+// The default is ConflictUpsert.
+func WithConflictPolicy(policy ConflictPolicy) StoreOption {
+	return func(config *storeConfig) {
+		config.conflictPolicy = policy
+	}
+}
+
+// WithLogger sets a function that is called after every query the store runs.
+// It is intended for tracing and debugging; by default no logging is done.
+func WithLogger(logger QueryLogger) StoreOption {
+	return func(config *storeConfig) {
+		config.logger = logger
+	}
+}
+
+// WithSkipUnmarshalErrors makes reads skip documents that cannot be unmarshaled into T,
+// reporting each of them to handler, instead of failing. It is meant for admin tooling
+// over data that may contain a few corrupt or outdated documents.
+func WithSkipUnmarshalErrors(handler UnmarshalErrorHandler) StoreOption {
+	return func(config *storeConfig) {
+		config.onUnmarshalError = handler
+	}
+}
+
+// WithNoAutoCreate makes NewStore skip all schema changes: the table, its indexes and
+// the row counter are assumed to already exist. This allows opening a store against a
+// read-only database or a replica whose connection lacks DDL rights. NewStore still
+// fails if the table does not exist. WithVerifyConfig still applies, reading the recorded
+// configuration without recording the new one.
+func WithNoAutoCreate() StoreOption {
+	return func(config *storeConfig) {
+		config.noAutoCreate = true
+	}
+}
+
+// WithoutKeyPopulation stops reads from setting the `litestore:"key"` field of returned
+// entities from the row key, saving a reflection call per row on hot read paths.
+// The field then holds whatever was stored in the json document, which is the key for
+// entities written by Save unless the field is excluded from json.
+func WithoutKeyPopulation() StoreOption {
+	return func(config *storeConfig) {
+		config.noKeyPopulation = true
+	}
+}
+
+// WithIDGenerator sets the function used to generate keys for entities saved without one.
+// The default generates random (version 4) UUIDs. The function must be safe for concurrent
+// use and must not return duplicates; see DeterministicIDs for reproducible keys in tests.
+func WithIDGenerator(gen IDGenerator) StoreOption {
+	return func(config *storeConfig) {
+		config.idGenerator = gen
+	}
+}
+
+// WithValidateJSON makes Save check every document with SQLite's own JSON parser and
+// fail instead of storing a document that SQLite's JSON functions cannot read, which would
+// otherwise make every json_extract on the row silently return NULL. Documents are also
+// stored in SQLite's canonical minified form.
+func WithValidateJSON() StoreOption {
+	return func(config *storeConfig) {
+		config.validateJSON = true
+	}
+}
+
+// WithDefaultOrder sets the ordering of queries that do not specify an OrderBy of their own.
+// Without it, such queries return rows in SQLite's unspecified order, which usually follows
+// insertion but may change after a VACUUM or between SQLite versions. Code that relies on a
+// stable order should either set OrderBy on every query or create the store with a default,
+// e.g. WithDefaultOrder(OrderBy{Key: "id", Direction: OrderAsc}) to order by the key field.
+// Queries with RandomOrder and no OrderBy are still shuffled.
+func WithDefaultOrder(orders ...OrderBy) StoreOption {
+	return func(config *storeConfig) {
+		config.defaultOrder = orders
+	}
+}
+
+// WithMaxScanRows caps the number of entities a query without a Limit of its own returns
+// at n, as a guardrail against a missing predicate pulling millions of rows into memory.
+// Queries that set Limit, including a Limit above n, are not affected. The cap is silent:
+// a query that hits it returns the first n entities without an error, so use a deliberate
+// Limit, Paginate or Export to read more. It also applies to the entities, but not the
+// total, returned by Page, and to VerifySchema with a sample size of 0.
+func WithMaxScanRows(n int) StoreOption {
+	return func(config *storeConfig) {
+		config.maxScanRows = n
+	}
+}
+
+// WithDisallowUnknownFields makes reads fail on stored documents that contain a field T
+// does not have, instead of silently dropping it as encoding/json does by default. It is
+// meant for services that must notice schema drift, e.g. data written by a newer version
+// of T that this version would lose on its next Save. Together with WithSkipUnmarshalErrors,
+// such documents are reported to the handler and skipped. It has no effect on map entities.
+func WithDisallowUnknownFields() StoreOption {
+	return func(config *storeConfig) {
+		config.strictDecoding = true
+	}
+}
+
+// WithMaxDocumentSize makes Save, SaveWithKey and Import reject, with ErrDocumentTooLarge,
+// an entity whose json document is larger than bytes. Huge documents slow down every query
+// that reads them and often point to a bug, such as a slice that grows without bound, so
+// failing the write is better than storing them. The blob and external fields are not
+// counted.
+func WithMaxDocumentSize(bytes int) StoreOption {
+	return func(config *storeConfig) {
+		config.maxDocumentSize = bytes
 	}
 }
 
 // NewStore creates a new Store instance for a given table name.
-// The generic type `T` must be a struct. If it contains a string field
+// The generic type `T` must be a struct or a pointer to a struct. If it contains a string field
 // with the struct tag `litestore:"key"`, this field will be used as the
 // primary key. If the tag is omitted, key will be generated automatically on Save.
+// The key field must be exported, since Save sets it on entities saved without a key.
+//
+// `T` can also be a map with string keys, e.g. map[string]any, to store documents whose
+// shape is not known in advance. Maps have no key field, so save them with SaveWithKey
+// to choose their key. Any key is accepted in filters, orders and indexes.
+//
+// A []byte field tagged with `litestore:"blob"` is stored as raw bytes in a separate blob
+// column instead of as base64 text inside the json document, which keeps the json small
+// for entities carrying large payloads such as images. The field is set on every entity
+// read with T, but as it is not part of the json document it cannot be used as a Filter,
+// OrderBy, index or update key; use BlobSize to query by its size. IterAs and IterRaw do not read it.
+//
+// Fields of any type tagged with `litestore:"external"` are kept out of the json document
+// too, in a separate external column holding a JSON object of all of them. This keeps the
+// queryable document small for entities that mix metadata with large opaque payloads.
+// Reads merge the external fields back into the document, but they cannot be used as
+// Filter, OrderBy, index or update keys. A null value nested in an object of an external
+// field is read back as missing, as SQLite's json_patch drops it when merging.
+//
+// A field tagged with `litestore:"default=value"` is set to value when an entity is read
+// from a document that lacks the field, e.g. one saved before the field was added to T, so
+// that new fields can get a default other than the zero value without migrating the data.
+// The value is used verbatim for string fields and parsed as JSON for other types, e.g.
+// `litestore:"default=10"` for an int. Queries see the stored documents, so Filter does not
+// match the default of documents that lack the field.
+//
+// A string field tagged with `litestore:"enum=active|suspended|deleted"` may only hold one
+// of the listed values: Save and SaveWithKey reject an entity with any other value. An
+// empty string is only accepted if listed, e.g. `litestore:"enum=|active"`. Writes that do
+// not go through T, such as UpdatePaths and Import, are not checked.
+//
+// A field tagged with `litestore:"required"` must be set: Save and SaveWithKey reject an
+// entity whose field holds its zero value, e.g. an empty string, a zero number, or a nil
+// pointer, slice or map, with an error naming the field. As with enums, other writes are
+// not checked.
+//
+// Unless created with WithNoAutoCreate, a store records its configuration, such as its
+// indexes, in the litestore_meta table, so that WithVerifyConfig can detect a store later
+// reopened with different settings.
 //
 // Options can be provided to configure the store:
 //   - WithIndex("fieldName"): Create an index on the specified JSON field
+//   - WithPartialIndex("fieldName", where): Create an index covering only rows matching where
+//   - WithSparseIndex("fieldName"): Create an index covering only rows where the field is set
+//   - WithConflictPolicy(policy): Choose upsert, fail or ignore semantics for Save
+//   - WithLogger(logger): Trace every query the store runs
+//   - WithCollation(name): Compare and order JSON fields using a collation
+//   - WithSkipUnmarshalErrors(handler): Skip and report documents that do not unmarshal into T
+//   - WithRowCounter(): Maintain a trigger-based row count for O(1) Len
+//   - WithNoAutoCreate(): Skip creating the table and indexes, e.g. on read-only databases
+//   - WithoutKeyPopulation(): Do not set the key field on read entities from the row key
+//   - WithIDGenerator(gen): Generate keys with gen instead of random UUIDs
+//   - WithRecordType(name): Share the table with other entity types, scoping the store to name
+//   - WithValidateJSON(): Reject documents that SQLite cannot parse as JSON on Save
+//   - WithMutationHook(hook): Call hook after every change to a single entity, e.g. for auditing
+//   - WithSchemaVersion(version): Stamp saved rows with the schema version of T
+//   - WithDefaultOrder(orders...): Order queries that specify no OrderBy
+//   - WithMaxScanRows(n): Limit queries that specify no Limit to n entities
+//   - WithDisallowUnknownFields(): Fail reads of documents with fields that T lacks
+//   - WithVerifyConfig(): Fail if the store's configuration differs from the recorded one
+//   - WithMaxDocumentSize(bytes): Reject writes of json documents larger than bytes
+//   - WithWriteRetry(maxAttempts, backoff): Retry saves that fail because the database is busy
 func NewStore[T any](ctx context.Context, db *sql.DB, tableName string, options ...StoreOption) (*Store[T], error) {
 	config := &storeConfig{}
 	for _, option := range options {
 		option(config)
 	}
 
-	return newStore[T](ctx, db, tableName, config.indexFields)
+	return newStore[T](ctx, db, tableName, config)
 }
 
-func newStore[T any](ctx context.Context, db *sql.DB, tableName string, indexFields []string) (*Store[T], error) {
+func newStore[T any](ctx context.Context, db *sql.DB, tableName string, config *storeConfig) (*Store[T], error) {
 	if !validTableNameRe.MatchString(tableName) {
 		return nil, fmt.Errorf("invalid table name: %s", tableName)
 	}
 
-	var zero T
-	typ := reflect.TypeOf(zero)
-	if typ.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("type T must be a struct, but got %s", typ.Kind())
+	if config.recordType != "" && !validTableNameRe.MatchString(config.recordType) {
+		return nil, fmt.Errorf("invalid record type: %s", config.recordType)
+	}
+
+	if config.schemaVersion < 0 {
+		return nil, fmt.Errorf("invalid schema version: %d", config.schemaVersion)
+	}
+
+	if config.maxScanRows < 0 {
+		return nil, fmt.Errorf("invalid max scan rows: %d", config.maxScanRows)
+	}
+
+	if config.maxDocumentSize < 0 {
+		return nil, fmt.Errorf("invalid max document size: %d", config.maxDocumentSize)
 	}
 
-	var keyField *reflect.StructField
-	var keyFieldJSONName string
+	if config.writeRetry.maxAttempts < 0 || config.writeRetry.backoff < 0 ||
+		(config.writeRetry.maxAttempts > 1 && config.writeRetry.backoff == 0) {
+		return nil, fmt.Errorf("invalid write retry: %d attempts with a backoff of %s", config.writeRetry.maxAttempts, config.writeRetry.backoff)
+	}
+
+	if config.collation != "" && !isKnownCollation(config.collation) {
+		return nil, fmt.Errorf("unknown collation: %s", config.collation)
+	}
+
+	typ := reflect.TypeFor[T]()
+	isPointer := false
+	if typ.Kind() == reflect.Pointer {
+		if typ.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("type T must be a struct or a pointer to a struct, but got %s", typ)
+		}
+		typ = typ.Elem()
+		isPointer = true
+	}
+	if typ.Kind() == reflect.Map && typ.Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("type T must be a map with string keys, but got %s", typ)
+	}
+	if typ.Kind() != reflect.Struct && typ.Kind() != reflect.Map {
+		return nil, fmt.Errorf("type T must be a struct or a map, but got %s", typ.Kind())
+	}
+	// Maps have no fields to reflect on: they have no key field, and any key is valid in queries.
+	numFields := 0
+	if typ.Kind() == reflect.Struct {
+		numFields = typ.NumField()
+	}
+
+	var keyField, blobField *reflect.StructField
+	var keyFieldJSONName, blobFieldJSONName string
+	var defaults []fieldDefault
+	var enums []fieldEnum
+	var required []fieldRequired
+	var externalFields map[string]struct{}
 	validJSONKeys := make(map[string]struct{})
 
-	for i := range typ.NumField() {
+	for i := range numFields {
 		field := typ.Field(i)
 
 		jsonTag := field.Tag.Get("json")
@@ -99,29 +538,129 @@ func newStore[T any](ctx context.Context, db *sql.DB, tableName string, indexFie
 			validJSONKeys[jsonName] = struct{}{}
 		}
 
-		if tag := field.Tag.Get("litestore"); tag == "key" {
+		switch field.Tag.Get("litestore") {
+		case "key":
 			if field.Type.Kind() != reflect.String {
 				return nil, fmt.Errorf("field with litestore:\"key\" tag must be a string, but field %s is %s", field.Name, field.Type.Kind())
 			}
+			// Save sets the key of new entities on the field, which reflection can only do
+			// for exported fields.
+			if !field.IsExported() {
+				return nil, fmt.Errorf("field with litestore:\"key\" tag must be exported, but field %s is not", field.Name)
+			}
 			f := field
 			keyField = &f
 			keyFieldJSONName = jsonName
+		case "blob":
+			if err := checkBlobField(field, blobField); err != nil {
+				return nil, err
+			}
+			f := field
+			blobField = &f
+			blobFieldJSONName = jsonName
+		case externalTag:
+			if err := checkExternalField(field, jsonName); err != nil {
+				return nil, err
+			}
+			if externalFields == nil {
+				externalFields = make(map[string]struct{})
+			}
+			externalFields[jsonName] = struct{}{}
+		case requiredTag:
+			r, err := newFieldRequired(field)
+			if err != nil {
+				return nil, err
+			}
+			required = append(required, r)
+		default:
+			if tag := field.Tag.Get("litestore"); strings.HasPrefix(tag, defaultTagPrefix) {
+				d, err := newFieldDefault(field, jsonName, tag)
+				if err != nil {
+					return nil, err
+				}
+				defaults = append(defaults, d)
+			} else if strings.HasPrefix(tag, enumTagPrefix) {
+				e, err := newFieldEnum(field, tag)
+				if err != nil {
+					return nil, err
+				}
+				enums = append(enums, e)
+			}
 		}
 	}
 
+	// A struct whose fields are all unexported or tagged json:"-" is stored as {}, so no
+	// query could ever match on its fields. The key and blob fields are stored in their
+	// own columns, so a struct with one of them still holds data.
+	if numFields > 0 && keyField == nil && blobField == nil && !hasJSONFields(typ) {
+		return nil, fmt.Errorf("type %s has no fields that encoding/json serializes: export them or remove their json:\"-\" tags", typ)
+	}
+
 	store := &Store[T]{
-		db:               db,
-		tableName:        tableName,
-		keyField:         keyField,
-		keyFieldJSONName: keyFieldJSONName,
-		validJSONKeys:    validJSONKeys,
+		db:                db,
+		tableName:         tableName,
+		keyField:          keyField,
+		keyFieldJSONName:  keyFieldJSONName,
+		blobField:         blobField,
+		blobFieldJSONName: blobFieldJSONName,
+		externalFields:    externalFields,
+		defaults:          defaults,
+		enums:             enums,
+		required:          required,
+		validJSONKeys:     validJSONKeys,
+		entityType:        typ,
+		isPointer:         isPointer,
+		populateKey:       keyField != nil && !config.noKeyPopulation,
+		newID:             config.idGenerator,
+		conflictPolicy:    config.conflictPolicy,
+		logger:            config.logger,
+		collation:         config.collation,
+		onUnmarshalError:  config.onUnmarshalError,
+		strictDecoding:    config.strictDecoding,
+		maxDocumentSize:   config.maxDocumentSize,
+		writeRetry:        config.writeRetry,
+		rowCounter:        config.rowCounter,
+		recordType:        config.recordType,
+		validateJSON:      config.validateJSON,
+		mutationHook:      config.mutationHook,
+		schemaVersion:     config.schemaVersion,
+		defaultOrder:      config.defaultOrder,
+		maxScanRows:       config.maxScanRows,
+		closer:            &storeCloser{},
+	}
+	if store.newID == nil {
+		store.newID = uuid.NewString
 	}
 
-	if err := store.init(ctx); err != nil {
-		return nil, err
+	if keyField != nil {
+		store.keyFieldIndex = keyField.Index[0]
 	}
-	if err := store.createIndexes(ctx, indexFields); err != nil {
-		return nil, fmt.Errorf("creating indexes for %s: %w", tableName, err)
+
+	store.indexNames = make(map[string]struct{}, len(config.indexes))
+	indexFields := make(map[string]string, len(config.indexes))
+	for _, idx := range config.indexes {
+		if idx.field != keyFieldJSONName || keyFieldJSONName == "" {
+			name := store.indexName(idx)
+			if field, ok := indexFields[name]; ok && field != idx.field {
+				return nil, fmt.Errorf("index fields %s and %s would both be indexed as %s", field, idx.field, name)
+			}
+			indexFields[name] = idx.field
+			store.indexNames[name] = struct{}{}
+		}
+	}
+
+	if _, _, err := (&Query{}).build(store.schema()); err != nil {
+		return nil, fmt.Errorf("invalid default order: %w", err)
+	}
+
+	if !config.noAutoCreate {
+		if err := store.setupSchema(ctx, config); err != nil {
+			return nil, err
+		}
+	} else if config.verifyConfig {
+		if err := store.verifyMeta(ctx); err != nil {
+			return nil, err
+		}
 	}
 	if err := store.prepareStatements(ctx); err != nil {
 		_ = store.Close()
@@ -131,7 +670,25 @@ func newStore[T any](ctx context.Context, db *sql.DB, tableName string, indexFie
 }
 
 // Close releases the prepared statements. It should be called when the store is no longer needed.
+// Afterwards, every method of the store and of its views returns ErrStoreClosed; iterators
+// obtained before Close can still be consumed. Closing a store again does nothing, and neither
+// does closing a view returned by WithinTx, as the statements belong to the store it was
+// created from.
+//
+// Close is safe to call concurrently with itself and with other methods, e.g. from several
+// shutdown paths. It waits for writes that are running a prepared statement to finish; any
+// method that has not started by then returns ErrStoreClosed.
 func (s *Store[T]) Close() error {
+	if s.tx != nil {
+		return nil
+	}
+	s.closer.mu.Lock()
+	defer s.closer.mu.Unlock()
+	if s.closer.closed {
+		return nil
+	}
+	s.closer.closed = true
+
 	var errStrings []string
 	stmts := []*sql.Stmt{s.saveStmt, s.deleteStmt}
 	for _, stmt := range stmts {
@@ -147,6 +704,25 @@ func (s *Store[T]) Close() error {
 	return nil
 }
 
+// Ping checks that the store is usable, e.g. for a readiness probe: it returns
+// ErrStoreClosed after Close, and an error if the database cannot be reached or the
+// store's table does not exist. It reads at most one row, so it is cheap on any table.
+func (s *Store[T]) Ping(ctx context.Context) error {
+	rows, err := s.queryContext(ctx, fmt.Sprintf("SELECT 1 FROM %s LIMIT 1", s.table()))
+	if err != nil {
+		return fmt.Errorf("pinging store: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("pinging store: %w", err)
+	}
+	return nil
+}
+
 // Save stores an entity in the database.
 // It takes a pointer to the entity to allow setting the key if a tagged field is present.
 // If the entity has a `litestore:"key"` field, Save acts as an "upsert":
@@ -155,64 +731,220 @@ func (s *Store[T]) Close() error {
 // If the entity has no `litestore:"key"` field, a new UUID is generated for each
 // Save call, effectively always inserting a new record. The generated ID is not
 // set on the struct.
+//
+// The upsert behavior can be changed with WithConflictPolicy: under ConflictFail,
+// saving an entity whose key already exists returns ErrUniqueViolation, and under
+// ConflictIgnore the existing entity is kept and Save returns nil.
 func (s *Store[T]) Save(ctx context.Context, entity *T) error {
-	if entity == nil {
-		return fmt.Errorf("cannot save a nil value")
+	entityValue, err := s.entityValue(entity)
+	if err != nil {
+		return err
 	}
-
-	stmt := s.saveStmt
-	if tx, ok := GetTx(ctx); ok {
-		stmt = tx.StmtContext(ctx, stmt)
-		defer stmt.Close()
+	if err := s.checkRequired(entityValue); err != nil {
+		return fmt.Errorf("invalid entity: %w", err)
+	}
+	if err := s.checkEnums(entityValue); err != nil {
+		return fmt.Errorf("invalid entity: %w", err)
 	}
 
 	var key string
 
 	if s.keyField != nil {
 		// A key field is present on the struct.
-		entityValue := reflect.ValueOf(entity).Elem()
-		keyFieldValue := entityValue.FieldByIndex(s.keyField.Index)
+		keyFieldValue := entityValue.Field(s.keyFieldIndex)
 
 		key = keyFieldValue.String()
 		if key == "" {
-			key = uuid.NewString()
-			if !keyFieldValue.CanSet() {
-				return fmt.Errorf("cannot set key on unexported field %s", s.keyField.Name)
-			}
+			key = s.newID()
 			keyFieldValue.SetString(key)
 		}
 	} else {
 		// No key field, so we always generate a new ID for insertion.
-		key = uuid.NewString()
+		key = s.newID()
+	}
+
+	return s.save(ctx, key, entity, entityValue)
+}
+
+// SaveWithKey stores an entity under the given key, acting as an "upsert" like Save does
+// for entities with a key field. It is meant for entity types without a `litestore:"key"`
+// field, such as maps, for which Save always inserts under a new key. If T has a key
+// field, it is set to key before saving.
+func (s *Store[T]) SaveWithKey(ctx context.Context, key string, entity *T) error {
+	if key == "" {
+		return fmt.Errorf("cannot save an entity with an empty key")
+	}
+	entityValue, err := s.entityValue(entity)
+	if err != nil {
+		return err
+	}
+	if err := s.checkRequired(entityValue); err != nil {
+		return fmt.Errorf("invalid entity: %w", err)
+	}
+	if err := s.checkEnums(entityValue); err != nil {
+		return fmt.Errorf("invalid entity: %w", err)
+	}
+	if s.keyField != nil {
+		entityValue.Field(s.keyFieldIndex).SetString(key)
+	}
+	return s.save(ctx, key, entity, entityValue)
+}
+
+// SaveManyFunc saves entities one by one like Save, all within a single transaction, and
+// reports progress to fn after each of them, e.g. to drive a progress bar when loading a
+// large import. fn receives the number of entities processed so far and the error saving
+// the last one, or nil. Returning true continues with the next entity, skipping the last
+// one if it failed; returning false stops the load. fn may be nil, in which case the load
+// stops at the first error.
+//
+// The transaction is rolled back, so that nothing is saved, if the load stops or ctx is
+// canceled between two entities. SaveManyFunc then returns the error of the last entity,
+// ErrSaveAborted if fn stopped it after a success, or the context's error. If ctx or the
+// store carries a transaction, the entities are saved within it instead, and rolling it
+// back is up to the caller. Key fields generated for the entities are kept on them even
+// if the transaction is rolled back.
+func (s *Store[T]) SaveManyFunc(ctx context.Context, entities []*T, fn func(done int, err error) bool) error {
+	if _, ok := s.txFor(ctx); ok {
+		return s.saveMany(ctx, entities, fn)
+	}
+	return WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		return s.saveMany(txCtx, entities, fn)
+	})
+}
+
+// saveMany implements SaveManyFunc within the transaction from ctx.
+func (s *Store[T]) saveMany(ctx context.Context, entities []*T, fn func(done int, err error) bool) error {
+	for i, entity := range entities {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("saving entities: %w", err)
+		}
+		err := s.Save(ctx, entity)
+		if fn == nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !fn(i+1, err) {
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("after %d entities: %w", i+1, ErrSaveAborted)
+		}
+	}
+	return nil
+}
+
+// entityValue returns the value that entity points to, dereferenced once more if T is
+// itself a pointer. It returns an error if there is no value to save.
+func (s *Store[T]) entityValue(entity *T) (reflect.Value, error) {
+	if entity == nil {
+		return reflect.Value{}, fmt.Errorf("cannot save a nil value")
 	}
+	entityValue := reflect.ValueOf(entity).Elem()
+	if s.isPointer {
+		if entityValue.IsNil() {
+			return reflect.Value{}, fmt.Errorf("cannot save a nil value")
+		}
+		entityValue = entityValue.Elem()
+	}
+	return entityValue, nil
+}
 
-	dataBytes, err := json.Marshal(entity)
+// save writes entity under key. entityValue is the value entity points to, as returned
+// by entityValue.
+func (s *Store[T]) save(ctx context.Context, key string, entity *T, entityValue reflect.Value) error {
+	var doc, blob []byte
+	var err error
+	if s.blobField != nil {
+		doc, blob, err = s.marshalWithoutBlob(entityValue)
+	} else {
+		doc, err = json.Marshal(entity)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal entity: %w", err)
 	}
+	var external []byte
+	if s.externalFields != nil {
+		if doc, external, err = s.splitExternal(doc); err != nil {
+			return err
+		}
+	}
+	if err := s.checkDocumentSize(key, doc); err != nil {
+		return err
+	}
 
-	_, err = stmt.ExecContext(ctx, key, dataBytes)
+	args := []any{key, doc}
+	if s.blobField != nil {
+		args = append(args, blob)
+	}
+	if s.externalFields != nil {
+		args = append(args, external)
+	}
+
+	res, err := s.execStmtWithRetry(ctx, s.saveStmt, s.saveSQL, args...)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("saving entity with id %s: %w: %w", key, ErrUniqueViolation, err)
+		}
 		return fmt.Errorf("saving entity with id %s: %w", key, err)
 	}
 
+	return s.notifyIfChanged(ctx, res, Mutation{Op: MutationSave, Key: key})
+}
+
+// checkDocumentSize returns ErrDocumentTooLarge if doc, the json document of the entity
+// with key, exceeds the limit set with WithMaxDocumentSize.
+func (s *Store[T]) checkDocumentSize(key string, doc []byte) error {
+	if s.maxDocumentSize > 0 && len(doc) > s.maxDocumentSize {
+		return fmt.Errorf("entity with key %s: %w: %d bytes, the limit is %d", key, ErrDocumentTooLarge, len(doc), s.maxDocumentSize)
+	}
 	return nil
 }
 
 // Delete removes an entity from the store by its key.
 func (s *Store[T]) Delete(ctx context.Context, key string) error {
-	stmt := s.deleteStmt
-	if tx, ok := GetTx(ctx); ok {
-		stmt = tx.StmtContext(ctx, stmt)
-		defer stmt.Close()
+	res, err := s.execStmt(ctx, s.deleteStmt, s.deleteSQL, key)
+	if err != nil {
+		return fmt.Errorf("deleting entity with key %s: %w", key, err)
 	}
 
-	_, err := stmt.ExecContext(ctx, key)
+	return s.notifyIfChanged(ctx, res, Mutation{Op: MutationDelete, Key: key})
+}
+
+// Rekey changes the key of an existing entity from oldKey to newKey in a single statement.
+// If T has a key field, its value in the stored document is updated as well.
+// It returns sql.ErrNoRows if there is no entity with oldKey, and ErrUniqueViolation
+// if an entity with newKey already exists.
+func (s *Store[T]) Rekey(ctx context.Context, oldKey, newKey string) error {
+	if newKey == "" {
+		return fmt.Errorf("new key cannot be empty")
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET "key" = ? WHERE %s`, s.table(), s.scoped(`"key" = ?`))
+	args := []any{newKey, oldKey}
+	if s.keyFieldJSONName != "" {
+		query = fmt.Sprintf(`UPDATE %s SET "key" = ?, "json" = json_set("json", ?, ?) WHERE %s`, s.table(), s.scoped(`"key" = ?`))
+		args = []any{newKey, "$." + s.keyFieldJSONName, newKey, oldKey}
+	}
+
+	res, err := s.execContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("deleting entity with key %s: %w", key, err)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("rekeying entity %s to %s: %w: %w", oldKey, newKey, ErrUniqueViolation, err)
+		}
+		return fmt.Errorf("rekeying entity %s to %s: %w", oldKey, newKey, err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rekeying entity %s to %s: %w", oldKey, newKey, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no entity with key %s: %w", oldKey, sql.ErrNoRows)
+	}
+
+	return s.notify(ctx, Mutation{Op: MutationRekey, Key: newKey, OldKey: oldKey})
 }
 
 // GetOne retrieves a single entity that matches the given predicate.
@@ -260,38 +992,267 @@ func (s *Store[T]) GetOne(ctx context.Context, p Predicate) (T, error) {
 	return result, nil
 }
 
+// GetOneOrdered retrieves the first entity matching q.Predicate in the order given by
+// q.OrderBy. Unlike GetOne, it is not an error for several entities to match: the
+// ordering decides which one is returned. q.Limit is ignored.
+// It returns sql.ErrNoRows if no entity is found.
+func (s *Store[T]) GetOneOrdered(ctx context.Context, q *Query) (T, error) {
+	var zero T
+	first := Query{Limit: 1}
+	if q != nil {
+		first = *q
+		first.Limit = 1
+	}
+	seq, err := s.Iter(ctx, &first)
+	if err != nil {
+		return zero, err
+	}
+
+	for entity, err := range seq {
+		if err != nil {
+			return zero, fmt.Errorf("iteration failed while getting one: %w", err)
+		}
+		return entity, nil
+	}
+
+	return zero, fmt.Errorf("no entity found matching predicate: %w", sql.ErrNoRows)
+}
+
+// Random retrieves one entity chosen at random among those matching p, e.g. to show a
+// random tip or a featured item. A nil predicate picks among all entities.
+// It returns sql.ErrNoRows if no entity is found. Like a query with RandomOrder, it reads
+// every matching row, so prefer a selective predicate on large stores.
+func (s *Store[T]) Random(ctx context.Context, p Predicate) (T, error) {
+	return s.GetOneOrdered(ctx, &Query{Predicate: p, RandomOrder: true})
+}
+
+// GetMany retrieves the entities with the given keys, keyed by their key.
+// Keys that do not exist are absent from the result; duplicate keys are fetched once.
+// Large key sets are fetched in several queries to stay below SQLite's parameter limit.
+func (s *Store[T]) GetMany(ctx context.Context, keys []string) (map[string]T, error) {
+	result := make(map[string]T, len(keys))
+
+	unique := make([]string, 0, len(keys))
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			unique = append(unique, key)
+		}
+	}
+
+	for chunk := range slices.Chunk(unique, getManyBatchSize) {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+		query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, s.schema().columns(), s.table(), s.scoped(`"key" IN (`+placeholders+")"))
+		args := make([]any, len(chunk))
+		for i, key := range chunk {
+			args[i] = key
+		}
+
+		rows, err := s.queryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("querying entities by key: %w", err)
+		}
+		for entity, err := range iterRows(ctx, rows, s.decodeWithKey) {
+			if err != nil {
+				return nil, fmt.Errorf("iteration failed while getting many: %w", err)
+			}
+			result[entity.Key] = entity.Value
+		}
+	}
+
+	return result, nil
+}
+
 // Iter returns an iterator over entities that match a given query.
 // If the query is nil, it iterates over all entities.
 // The iterator yields an entity and an error for each item.
 func (s *Store[T]) Iter(ctx context.Context, q *Query) (iter.Seq2[T, error], error) {
+	rows, err := s.query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return iterRows(ctx, rows, s.decode), nil
+}
+
+// Result is an entity or the error that ended a Stream.
+type Result[V any] struct {
+	Value V
+	Err   error
+}
+
+// Stream is like Iter, but sends the entities matching q on a channel, which makes it easy
+// to fan them out to a pool of worker goroutines. If reading fails, a Result carrying the
+// error is sent last. The channel is closed, and the underlying rows released, once all
+// entities have been sent or ctx is cancelled. Callers that stop reading early must cancel
+// ctx, or the goroutine feeding the channel is leaked.
+func (s *Store[T]) Stream(ctx context.Context, q *Query) (<-chan Result[T], error) {
+	seq, err := s.Iter(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Result[T])
+	go func() {
+		defer close(ch)
+		for entity, err := range seq {
+			select {
+			case ch <- Result[T]{Value: entity, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// IterAs is like Store.Iter, but unmarshals each stored document into V instead of T.
+// V is typically a lighter "view" struct with a subset of T's fields: json fields that
+// V does not declare are ignored. The key field of T is not populated on V; declare a
+// field with the same json name on V to receive it from the stored document.
+func IterAs[V, T any](ctx context.Context, s *Store[T], q *Query) (iter.Seq2[V, error], error) {
+	rows, err := s.query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	decode := func(_ string, jsonData string, _ []byte) (V, error) {
+		var v V
+		if err := json.Unmarshal([]byte(jsonData), &v); err != nil {
+			var zero V
+			return zero, fmt.Errorf("unmarshaling entity data: %w", err)
+		}
+		return v, nil
+	}
+	return iterRows(ctx, rows, decode), nil
+}
+
+// Pair is a stored entity together with its key.
+type Pair[V any] struct {
+	Key   string
+	Value V
+}
+
+// IterRaw is like Iter, but yields the stored json documents with their keys without
+// unmarshaling them into T. It is meant for migration and re-encoding tooling that
+// has to process documents which may no longer match T.
+func (s *Store[T]) IterRaw(ctx context.Context, q *Query) (iter.Seq2[Pair[json.RawMessage], error], error) {
+	rows, err := s.query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	decode := func(key string, jsonData string, _ []byte) (Pair[json.RawMessage], error) {
+		return Pair[json.RawMessage]{Key: key, Value: json.RawMessage(jsonData)}, nil
+	}
+	return iterRows(ctx, rows, decode), nil
+}
+
+// Row is a stored entity together with its key and the rowid of its row.
+type Row[V any] struct {
+	RowID int64
+	Key   string
+	Value V
+}
+
+// IterWithRowID is like Iter, but yields each entity with its key and the SQLite rowid of
+// its row, e.g. to correlate entities with an FTS or other external table keyed by rowid.
+// The rowid of an entity is kept by Save and Rekey, but it is not a stable identifier: a
+// VACUUM may renumber the rows, and an entity that is deleted and saved again gets a new one.
+func (s *Store[T]) IterWithRowID(ctx context.Context, q *Query) (iter.Seq2[Row[T], error], error) {
 	if q == nil {
-		// To simplify logic, a nil query is equivalent to an empty query.
 		q = &Query{}
 	}
-
-	querySQL, args, err := q.build(s.tableName, s.validJSONKeys, s.keyFieldJSONName)
+	sc := s.schema()
+	querySQL, args, err := q.buildSelect(sc, `"rowid", `+sc.columns())
 	if err != nil {
 		return nil, fmt.Errorf("building query: %w", err)
 	}
+	rows, err := s.queryContext(ctx, querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying entities with predicate: %w", err)
+	}
 
-	var rows *sql.Rows
-	var queryErr error
+	var rowID int64
+	return iterRowsWith(ctx, rows, []any{&rowID}, func(key string, jsonData string, blob []byte) (Row[T], error) {
+		t, err := s.decode(key, jsonData, blob)
+		if err != nil {
+			return Row[T]{}, err
+		}
+		return Row[T]{RowID: rowID, Key: key, Value: t}, nil
+	}), nil
+}
 
-	if tx, ok := GetTx(ctx); ok {
-		rows, queryErr = tx.QueryContext(ctx, querySQL, args...)
-	} else {
-		rows, queryErr = s.db.QueryContext(ctx, querySQL, args...)
+// IterRecent yields the limit most recently inserted entities, newest first, using the
+// SQLite rowid as a proxy for insertion time. It works on stores with and without a key
+// field and needs no timestamp field: reading the rows by descending rowid is a scan of the
+// table's own b-tree, not a sort. Saving an existing entity again keeps its rowid, so it
+// does not count as a new insertion.
+//
+// The order is only approximate: a VACUUM may renumber the rows, and after the newest
+// entity is deleted its rowid may be given to the next one inserted. Use a timestamp field
+// where insertion order must be exact.
+func (s *Store[T]) IterRecent(ctx context.Context, limit int) (iter.Seq2[T, error], error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid limit: %d", limit)
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s`, s.schema().columns(), s.table())
+	if scope := s.schema().scope(); scope != "" {
+		query += " WHERE " + scope
 	}
+	query += ` ORDER BY "rowid" DESC LIMIT ?`
 
-	if queryErr != nil {
-		return nil, fmt.Errorf("querying entities with predicate: %w", queryErr)
+	rows, err := s.queryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying recent entities: %w", err)
 	}
+	return iterRows(ctx, rows, s.decode), nil
+}
+
+// CompileQuery returns the SQL and arguments that Iter would run for q, without running it.
+// It is meant for debugging and for verifying that a predicate tree compiles.
+// A nil query compiles to a select of all entities.
+func (s *Store[T]) CompileQuery(q *Query) (string, []any, error) {
+	if q == nil {
+		q = &Query{}
+	}
+	return q.build(s.schema())
+}
+
+// query builds and runs the SELECT for q, returning rows of (key, json).
+// A nil query selects all entities.
+func (s *Store[T]) query(ctx context.Context, q *Query) (*sql.Rows, error) {
+	querySQL, args, err := s.CompileQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("building query: %w", err)
+	}
+
+	rows, err := s.queryContext(ctx, querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying entities with predicate: %w", err)
+	}
+	return rows, nil
+}
+
+// iterRows wraps rows of (key, json) into an iterator that decodes each row with decode.
+// Rows that also select the blob column pass it to decode; otherwise decode gets nil.
+// The rows are closed when iteration finishes or is stopped early.
+func iterRows[V any](ctx context.Context, rows *sql.Rows, decode func(key string, jsonData string, blob []byte) (V, error)) iter.Seq2[V, error] {
+	return iterRowsWith(ctx, rows, nil, decode)
+}
 
-	seq := func(yield func(T, error) bool) {
+// iterRowsWith is like iterRows for rows that select extra columns before the key, which
+// are scanned into lead ahead of each call to decode.
+func iterRowsWith[V any](ctx context.Context, rows *sql.Rows, lead []any, decode func(key string, jsonData string, blob []byte) (V, error)) iter.Seq2[V, error] {
+	return func(yield func(V, error) bool) {
 		defer func() {
 			_ = rows.Close()
 		}()
-		var zero T
+		var zero V
+
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(zero, fmt.Errorf("reading row columns: %w", err))
+			return
+		}
 
 		for rows.Next() {
 			if err := ctx.Err(); err != nil {
@@ -299,27 +1260,26 @@ func (s *Store[T]) Iter(ctx context.Context, q *Query) (iter.Seq2[T, error], err
 				return
 			}
 			var key, jsonData string
-			if scanErr := rows.Scan(&key, &jsonData); scanErr != nil {
+			var blob []byte
+			dest := append(slices.Clip(lead), &key, &jsonData)
+			if len(columns) > len(dest) {
+				dest = append(dest, &blob)
+			}
+			if scanErr := rows.Scan(dest...); scanErr != nil {
 				yield(zero, fmt.Errorf("scanning entity data row: %w", scanErr))
 				return
 			}
 
-			var t T
-			if unmarshalErr := json.Unmarshal([]byte(jsonData), &t); unmarshalErr != nil {
-				yield(zero, fmt.Errorf("unmarshaling entity data: %w", unmarshalErr))
-				return
+			v, decodeErr := decode(key, jsonData, blob)
+			if errors.Is(decodeErr, errSkipRow) {
+				continue
 			}
-
-			// If the struct has a key field, populate it with the database key
-			if s.keyField != nil {
-				entityValue := reflect.ValueOf(&t).Elem()
-				keyFieldValue := entityValue.FieldByIndex(s.keyField.Index)
-				if keyFieldValue.CanSet() {
-					keyFieldValue.SetString(key)
-				}
+			if decodeErr != nil {
+				yield(zero, decodeErr)
+				return
 			}
 
-			if !yield(t, nil) {
+			if !yield(v, nil) {
 				return
 			}
 		}
@@ -328,39 +1288,484 @@ func (s *Store[T]) Iter(ctx context.Context, q *Query) (iter.Seq2[T, error], err
 			yield(zero, fmt.Errorf("during row iteration: %w", iterErr))
 		}
 	}
+}
+
+// Page returns the entities matching q together with the total number of entities
+// matching q's predicate, ignoring its Limit. It is meant for list views that show a
+// page of results along with a "1-20 of 347" style total. A nil query returns all
+// entities.
+//
+// The count and the page are read with two queries. Pass a ctx carrying a transaction
+// to have both see the same snapshot of the table.
+func (s *Store[T]) Page(ctx context.Context, q *Query) ([]T, int, error) {
+	if q == nil {
+		q = &Query{}
+	}
+	sc := s.schema()
+
+	where, whereArgs, err := q.where(sc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("building query: %w", err)
+	}
+	total, err := s.count(ctx, where, whereArgs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	seq, err := s.Iter(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	var items []T
+	for entity, err := range seq {
+		if err != nil {
+			return nil, 0, fmt.Errorf("iteration failed while getting page: %w", err)
+		}
+		items = append(items, entity)
+	}
+
+	return items, total, nil
+}
+
+// count returns the number of rows matching where, a WHERE clause as built by Query.where.
+func (s *Store[T]) count(ctx context.Context, where string, args []any) (int, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", s.table(), where)
+	rows, err := s.queryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("counting entities: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("counting entities: %w", err)
+		}
+		return 0, fmt.Errorf("counting entities: no result")
+	}
+	var n int
+	if err := rows.Scan(&n); err != nil {
+		return 0, fmt.Errorf("scanning entity count: %w", err)
+	}
+	return n, nil
+}
+
+// Paginate returns up to limit entities matching the predicate whose keys come
+// after cursor, ordered by key. It implements keyset pagination over the primary
+// key column, so each page is an index range scan regardless of how deep it is.
+//
+// Pass an empty cursor to fetch the first page. The returned cursor is the key of
+// the last entity in the page and should be passed to the next call; it is empty
+// when there are no more pages.
+func (s *Store[T]) Paginate(ctx context.Context, p Predicate, cursor string, limit int) ([]T, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid page limit: %d", limit)
+	}
 
-	return seq, nil
+	var conditions []string
+	var args []any
+
+	if p != nil {
+		whereClause, whereArgs, err := buildWhereClause(p, s.schema())
+		if err != nil {
+			return nil, "", fmt.Errorf("building query: %w", err)
+		}
+		if whereClause != "" {
+			conditions = append(conditions, "("+whereClause+")")
+			args = append(args, whereArgs...)
+		}
+	}
+	if cursor != "" {
+		conditions = append(conditions, `"key" > ?`)
+		args = append(args, cursor)
+	}
+	if scope := s.schema().scope(); scope != "" {
+		conditions = append(conditions, scope)
+	}
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString(fmt.Sprintf(`SELECT %s FROM %s`, s.schema().columns(), s.table()))
+	if len(conditions) > 0 {
+		queryBuilder.WriteString(" WHERE ")
+		queryBuilder.WriteString(strings.Join(conditions, " AND "))
+	}
+	// Fetch one extra row to find out whether there is a next page.
+	queryBuilder.WriteString(` ORDER BY "key" ASC LIMIT ?`)
+	args = append(args, limit+1)
+
+	rows, err := s.queryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("querying page: %w", err)
+	}
+
+	// Rows skipped by an UnmarshalErrorHandler still count towards the page and move the
+	// cursor, so that a page of bad rows does not end the pagination early. The extra row
+	// only tells that there is a next page.
+	var page []T
+	var lastKey string
+	scanned := 0
+	hasMore := false
+	decode := func(key string, jsonData string, blob []byte) (T, error) {
+		if scanned == limit {
+			hasMore = true
+			var zero T
+			return zero, errSkipRow
+		}
+		scanned++
+		lastKey = key
+		return s.decode(key, jsonData, blob)
+	}
+	for t, err := range iterRows(ctx, rows, decode) {
+		if err != nil {
+			return nil, "", err
+		}
+		page = append(page, t)
+	}
+
+	if !hasMore {
+		return page, "", nil
+	}
+	return page, lastKey, nil
+}
+
+// IterBatched is like Iter over the entities matching p, but reads them in batches of
+// batchSize with Paginate instead of in a single query. Each batch releases its connection
+// before the batch is yielded, so a slow consumer of a large result set does not hold a
+// connection of the pool for the whole iteration. Entities are yielded in key order.
+//
+// Unless ctx carries a transaction, every batch reads its own snapshot of the table, so
+// the iteration as a whole is not isolated from concurrent writes: entities saved or
+// deleted meanwhile may or may not be yielded, depending on where their key falls, and
+// an entity rekeyed meanwhile may be yielded twice or not at all.
+func (s *Store[T]) IterBatched(ctx context.Context, p Predicate, batchSize int) (iter.Seq2[T, error], error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size: %d", batchSize)
+	}
+	return func(yield func(T, error) bool) {
+		var zero T
+		cursor := ""
+		for {
+			batch, next, err := s.Paginate(ctx, p, cursor, batchSize)
+			if err != nil {
+				yield(zero, fmt.Errorf("reading batch after key %q: %w", cursor, err))
+				return
+			}
+			for _, entity := range batch {
+				if !yield(entity, nil) {
+					return
+				}
+			}
+			if next == "" {
+				return
+			}
+			cursor = next
+		}
+	}, nil
+}
+
+// table returns the quoted name of the store's table for use in SQL.
+func (s *Store[T]) table() string {
+	return quoteIdent(s.tableName)
+}
+
+// schema describes the store to the query builder.
+func (s *Store[T]) schema() querySchema {
+	return querySchema{
+		tableName:    s.tableName,
+		validKeys:    s.validJSONKeys,
+		entityType:   s.entityType,
+		keyFieldName: s.keyFieldJSONName,
+		collation:    s.collation,
+		recordType:   s.recordType,
+		defaultOrder: s.defaultOrder,
+		maxRows:      s.maxScanRows,
+		indexes:      s.indexNames,
+		blob:         s.blobField != nil,
+		blobKey:      s.blobFieldJSONName,
+		external:     s.externalFields,
+	}
+}
+
+// queryContext runs a query within the transaction from ctx or the store's own
+// transaction if there is one, or directly against the database otherwise.
+func (s *Store[T]) queryContext(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	if s.closer.isClosed() {
+		return nil, ErrStoreClosed
+	}
+	defer s.logQuery(query, args, time.Now(), &err)
+
+	if tx, ok := s.txFor(ctx); ok {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	return s.db.QueryContext(ctx, query, args...)
+}
+
+// queryRow runs a query returning a single row within the transaction from ctx or the
+// store's own, and scans the row into dest. It returns sql.ErrNoRows if there is no row.
+func (s *Store[T]) queryRow(ctx context.Context, dest []any, query string, args ...any) (err error) {
+	if s.closer.isClosed() {
+		return ErrStoreClosed
+	}
+	defer s.logQuery(query, args, time.Now(), &err)
+
+	if tx, ok := s.txFor(ctx); ok {
+		return tx.QueryRowContext(ctx, query, args...).Scan(dest...)
+	}
+	return s.db.QueryRowContext(ctx, query, args...).Scan(dest...)
+}
+
+// execContext executes a statement within the transaction from ctx or the store's own
+// transaction if there is one, or directly against the database otherwise.
+func (s *Store[T]) execContext(ctx context.Context, query string, args ...any) (res sql.Result, err error) {
+	if s.closer.isClosed() {
+		return nil, ErrStoreClosed
+	}
+	defer s.logQuery(query, args, time.Now(), &err)
+
+	if tx, ok := s.txFor(ctx); ok {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	return s.db.ExecContext(ctx, query, args...)
+}
+
+// execStmt executes a prepared statement, rebinding it to the transaction from ctx or the
+// store's own transaction if there is one.
+// query is the SQL the statement was prepared from and is only used for logging.
+func (s *Store[T]) execStmt(ctx context.Context, stmt *sql.Stmt, query string, args ...any) (res sql.Result, err error) {
+	// The read lock keeps Close from closing stmt while it runs.
+	s.closer.mu.RLock()
+	defer s.closer.mu.RUnlock()
+	if s.closer.closed {
+		return nil, ErrStoreClosed
+	}
+	defer s.logQuery(query, args, time.Now(), &err)
+
+	if tx, ok := s.txFor(ctx); ok {
+		stmt = tx.StmtContext(ctx, stmt)
+		defer stmt.Close()
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+// logQuery reports a finished query to the configured logger, if any.
+// It takes a pointer to the error so that it can be deferred.
+func (s *Store[T]) logQuery(query string, args []any, start time.Time, err *error) {
+	if s.logger != nil {
+		s.logger(query, args, time.Since(start), *err)
+	}
+}
+
+// unmarshal decodes a stored json document into v, rejecting fields that v does not
+// have if the store was created with WithDisallowUnknownFields.
+func (s *Store[T]) unmarshal(jsonData string, v any) error {
+	if !s.strictDecoding {
+		return json.Unmarshal([]byte(jsonData), v)
+	}
+	dec := json.NewDecoder(strings.NewReader(jsonData))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// decodeWithKey is like decode, but returns the entity paired with its key.
+func (s *Store[T]) decodeWithKey(key string, jsonData string, blob []byte) (Pair[T], error) {
+	t, err := s.decode(key, jsonData, blob)
+	if err != nil {
+		return Pair[T]{}, err
+	}
+	return Pair[T]{Key: key, Value: t}, nil
+}
+
+// decode unmarshals a stored json document into T and, if T has a key field and key
+// population is enabled, populates it with the database key. Fields with a default that are
+// missing from the document are set to their default. If T has a blob field, it is
+// set to blob. If the document cannot be unmarshaled and
+// an UnmarshalErrorHandler is configured, the handler is called and errSkipRow is returned.
+func (s *Store[T]) decode(key string, jsonData string, blob []byte) (T, error) {
+	return s.decodeWith(key, jsonData, blob, s.onUnmarshalError)
+}
+
+// decodeStrictWithKey is like decodeWithKey, but fails on a document that cannot be
+// unmarshaled even if an UnmarshalErrorHandler is configured, for reads that must not
+// skip any row.
+func (s *Store[T]) decodeStrictWithKey(key string, jsonData string, blob []byte) (Pair[T], error) {
+	t, err := s.decodeWith(key, jsonData, blob, nil)
+	if err != nil {
+		return Pair[T]{}, err
+	}
+	return Pair[T]{Key: key, Value: t}, nil
+}
+
+// decodeWith implements decode, reporting documents that cannot be unmarshaled to
+// onUnmarshalError if it is not nil.
+func (s *Store[T]) decodeWith(key string, jsonData string, blob []byte, onUnmarshalError UnmarshalErrorHandler) (T, error) {
+	var t T
+	if err := s.unmarshal(jsonData, &t); err != nil {
+		var zero T
+		if onUnmarshalError != nil {
+			onUnmarshalError(key, []byte(jsonData), err)
+			return zero, errSkipRow
+		}
+		return zero, fmt.Errorf("unmarshaling entity data: %w", err)
+	}
+
+	if s.populateKey || s.blobField != nil || len(s.defaults) > 0 {
+		entityValue := reflect.ValueOf(&t).Elem()
+		if s.isPointer {
+			// A stored "null" leaves the pointer nil, so there are no fields to populate.
+			if entityValue.IsNil() {
+				return t, nil
+			}
+			entityValue = entityValue.Elem()
+		}
+		if len(s.defaults) > 0 {
+			if err := s.applyDefaults(entityValue, jsonData); err != nil {
+				var zero T
+				return zero, fmt.Errorf("applying defaults: %w", err)
+			}
+		}
+		if s.populateKey {
+			entityValue.Field(s.keyFieldIndex).SetString(key)
+		}
+		if s.blobField != nil {
+			entityValue.Field(s.blobField.Index[0]).SetBytes(blob)
+		}
+	}
+
+	return t, nil
+}
+
+// schemaLocks holds a lock per table whose schema is being set up by a store. A lock is
+// removed once no store waits for it, so that closed databases are not kept referenced.
+var schemaLocks = struct {
+	sync.Mutex
+	locks map[schemaLockKey]*schemaLock
+}{locks: make(map[schemaLockKey]*schemaLock)}
+
+// schemaLockKey identifies a table within a database.
+type schemaLockKey struct {
+	db    *sql.DB
+	table string
+}
+
+// schemaLock serializes the schema setup of a table. refs counts the stores holding or
+// waiting for it, and is guarded by schemaLocks.
+type schemaLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lockSchema takes the schema lock of key and returns a function releasing it.
+func lockSchema(key schemaLockKey) func() {
+	schemaLocks.Lock()
+	l, ok := schemaLocks.locks[key]
+	if !ok {
+		l = &schemaLock{}
+		schemaLocks.locks[key] = l
+	}
+	l.refs++
+	schemaLocks.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		schemaLocks.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(schemaLocks.locks, key)
+		}
+		schemaLocks.Unlock()
+	}
+}
+
+// setupSchema creates the store's table, its columns, indexes and row counter if they do
+// not exist yet, and records the store's configuration. Several of these steps check the
+// schema before changing it, so stores created concurrently for the same table would race
+// each other; setup is serialized per database and table to prevent that. Stores opened
+// by other processes are not covered.
+func (s *Store[T]) setupSchema(ctx context.Context, config *storeConfig) error {
+	unlock := lockSchema(schemaLockKey{db: s.db, table: s.tableName})
+	defer unlock()
+
+	if config.verifyConfig {
+		if err := s.verifyMeta(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.init(ctx); err != nil {
+		return err
+	}
+	if s.schemaVersion != 0 {
+		if err := s.initSchemaVersion(ctx); err != nil {
+			return fmt.Errorf("adding schema version column to %s: %w", s.tableName, err)
+		}
+	}
+	if s.blobField != nil {
+		if err := s.initBlobColumn(ctx); err != nil {
+			return fmt.Errorf("adding blob column to %s: %w", s.tableName, err)
+		}
+	}
+	if s.externalFields != nil {
+		if err := s.initExternalColumn(ctx); err != nil {
+			return fmt.Errorf("adding external column to %s: %w", s.tableName, err)
+		}
+	}
+	if err := s.createIndexes(ctx, config.indexes); err != nil {
+		return fmt.Errorf("creating indexes for %s: %w", s.tableName, err)
+	}
+	if s.rowCounter {
+		if err := s.initRowCounter(ctx); err != nil {
+			return fmt.Errorf("creating row counter for %s: %w", s.tableName, err)
+		}
+	}
+	if err := s.recordMeta(ctx); err != nil {
+		return fmt.Errorf("recording config of %s: %w", s.tableName, err)
+	}
+	return nil
 }
 
 func (s *Store[T]) init(ctx context.Context) error {
+	if s.recordType != "" {
+		query := fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				"key" TEXT NOT NULL,
+				"record_type" TEXT NOT NULL,
+				"json" TEXT NOT NULL,
+				PRIMARY KEY ("record_type", "key")
+			)`, s.table())
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("creating table %s: %w", s.tableName, err)
+		}
+		return s.checkRecordTypeColumn(ctx)
+	}
+
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
-			key TEXT PRIMARY KEY,
-			json TEXT NOT NULL
-		)`, s.tableName)
+			"key" TEXT PRIMARY KEY,
+			"json" TEXT NOT NULL
+		)`, s.table())
 	if _, err := s.db.ExecContext(ctx, query); err != nil {
 		return fmt.Errorf("creating table %s: %w", s.tableName, err)
 	}
 	return nil
 }
 
-func (s *Store[T]) createIndexes(ctx context.Context, indexFields []string) error {
-	if len(indexFields) == 0 {
+func (s *Store[T]) createIndexes(ctx context.Context, indexes []indexSpec) error {
+	if len(indexes) == 0 {
 		return nil
 	}
 
 	// Validate that all index fields are valid JSON keys for this type
-	for _, field := range indexFields {
+	for _, idx := range indexes {
+		field := idx.field
 		if s.keyFieldJSONName != "" && field == s.keyFieldJSONName {
 			// Skip key field - it's already indexed as primary key
 			continue
 		}
 
-		// Only validate top-level keys. Nested keys (e.g. 'a.b') are not validated.
-		if !strings.Contains(field, ".") {
-			if _, ok := s.validJSONKeys[field]; !ok {
-				return fmt.Errorf("invalid index field: '%s' is not a valid key for this entity", field)
-			}
+		if !s.schema().hasKey(field) {
+			return fmt.Errorf("invalid index field: '%s' is not a valid key for this entity", field)
 		}
 
 		// Validate field name for SQL safety (similar to query.go validation)
@@ -370,14 +1775,48 @@ func (s *Store[T]) createIndexes(ctx context.Context, indexFields []string) erro
 	}
 
 	// Create indexes for each field
-	for _, field := range indexFields {
+	for _, idx := range indexes {
+		field := idx.field
 		if s.keyFieldJSONName != "" && field == s.keyFieldJSONName {
 			continue // Skip key field - it's already indexed as primary key
 		}
 
-		indexName := fmt.Sprintf("idx_%s_%s", s.tableName, field)
-		jsonPath := "$." + field
-		createIndexSQL := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(json_extract(json, '%s'))", indexName, s.tableName, jsonPath)
+		indexName := s.indexName(idx)
+		createIndexSQL := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(%s)", quoteIdent(indexName), s.table(), s.schema().indexValue(field))
+
+		// In a shared table, each record type gets its own index covering only its rows.
+		scope := s.schema().scope()
+		if scope != "" {
+			createIndexSQL = fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(%s) WHERE %s", quoteIdent(indexName), s.table(), s.schema().indexValue(field), scope)
+		}
+
+		if idx.where != nil {
+			// SQLite does not allow bound parameters in the WHERE clause of an index,
+			// so the predicate values are inlined as literals.
+			whereClause, whereArgs, err := buildWhereClause(idx.where, s.schema())
+			if err != nil {
+				return fmt.Errorf("building partial index condition for %s: %w", field, err)
+			}
+			if whereClause == "" {
+				return fmt.Errorf("partial index condition for %s is empty", field)
+			}
+			whereSQL, err := inlineArgs(whereClause, whereArgs)
+			if err != nil {
+				return fmt.Errorf("building partial index condition for %s: %w", field, err)
+			}
+			if scope != "" {
+				whereSQL = "(" + whereSQL + ") AND " + scope
+			}
+			createIndexSQL = fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(%s) WHERE %s", quoteIdent(indexName), s.table(), s.schema().indexValue(field), whereSQL)
+		}
+
+		if idx.sparse {
+			whereSQL := s.schema().indexValue(field) + " IS NOT NULL"
+			if scope != "" {
+				whereSQL += " AND " + scope
+			}
+			createIndexSQL = fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(%s) WHERE %s", quoteIdent(indexName), s.table(), s.schema().indexValue(field), whereSQL)
+		}
 
 		if _, err := s.db.ExecContext(ctx, createIndexSQL); err != nil {
 			return fmt.Errorf("creating index %s: %w", indexName, err)
@@ -387,23 +1826,93 @@ func (s *Store[T]) createIndexes(ctx context.Context, indexFields []string) erro
 	return nil
 }
 
+// indexName returns the name of the index created for idx. The characters of a nested
+// field path that are not valid in an identifier are replaced with underscores, so an
+// index on "address.city" is named idx_<table>_address_city.
+func (s *Store[T]) indexName(idx indexSpec) string {
+	field := indexNameUnsafeRe.ReplaceAllString(idx.field, "_")
+	name := fmt.Sprintf("idx_%s_%s", s.tableName, field)
+	if s.recordType != "" {
+		name = fmt.Sprintf("idx_%s_%s_%s", s.tableName, s.recordType, field)
+	}
+	switch {
+	case idx.where != nil:
+		name += "_partial"
+	case idx.sparse:
+		name += "_sparse"
+	}
+	return name
+}
+
 func (s *Store[T]) prepareStatements(ctx context.Context) (err error) {
 	// Prepare Save
-	querySave := fmt.Sprintf(`
-		INSERT INTO %s (key, json)
-		VALUES (?, ?)
-		ON CONFLICT(key) DO UPDATE SET
-			json = excluded.json
-	`, s.tableName)
-	if s.saveStmt, err = s.db.PrepareContext(ctx, querySave); err != nil {
+	// json() fails with "malformed JSON" for documents SQLite cannot parse.
+	jsonValue := "?"
+	if s.validateJSON {
+		jsonValue = "json(?)"
+	}
+	// The blob field is stored in its own column, so its null placeholder is dropped.
+	if s.blobFieldJSONName != "" {
+		jsonValue = "json_remove(" + jsonValue + ", " + quoteLiteral("$."+s.blobFieldJSONName) + ")"
+	}
+	conflictTarget := `"key"`
+	columns, values := `"key", "json"`, "?, "+jsonValue
+	if s.recordType != "" {
+		conflictTarget = `"record_type", "key"`
+		columns, values = `"key", "record_type", "json"`, "?, "+quoteLiteral(s.recordType)+", "+jsonValue
+	}
+	updateSet := `"json" = excluded."json"`
+	if s.schemaVersion != 0 {
+		columns += `, "schema_version"`
+		values += fmt.Sprintf(", %d", s.schemaVersion)
+		updateSet += `, "schema_version" = excluded."schema_version"`
+	}
+	if s.blobField != nil {
+		columns += `, "blob"`
+		values += ", ?"
+		updateSet += `, "blob" = excluded."blob"`
+	}
+	if s.externalFields != nil {
+		columns += `, "external"`
+		values += ", ?"
+		updateSet += `, "external" = excluded."external"`
+	}
+
+	var onConflict string
+	switch s.conflictPolicy {
+	case ConflictUpsert:
+		onConflict = fmt.Sprintf("ON CONFLICT(%s) DO UPDATE SET %s", conflictTarget, updateSet)
+	case ConflictIgnore:
+		onConflict = fmt.Sprintf("ON CONFLICT(%s) DO NOTHING", conflictTarget)
+	case ConflictFail:
+		// No conflict clause: a duplicate key violates the primary key constraint.
+	default:
+		return fmt.Errorf("unknown conflict policy: %d", s.conflictPolicy)
+	}
+	s.saveSQL = fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES (%s)
+		%s
+	`, s.table(), columns, values, onConflict)
+	if s.saveStmt, err = s.db.PrepareContext(ctx, s.saveSQL); err != nil {
 		return fmt.Errorf("preparing save statement: %w", err)
 	}
 
 	// Prepare Delete
-	queryDelete := fmt.Sprintf("DELETE FROM %s WHERE key = ?", s.tableName)
-	if s.deleteStmt, err = s.db.PrepareContext(ctx, queryDelete); err != nil {
+	s.deleteSQL = fmt.Sprintf("DELETE FROM %s WHERE %s", s.table(), s.scoped(`"key" = ?`))
+	if s.deleteStmt, err = s.db.PrepareContext(ctx, s.deleteSQL); err != nil {
 		return fmt.Errorf("preparing delete statement: %w", err)
 	}
 
 	return nil
 }
+
+// isUniqueViolation reports whether err is a SQLite primary key or unique constraint failure.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
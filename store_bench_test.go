@@ -0,0 +1,65 @@
+package litestore_test
+
+import (
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func BenchmarkStore_Save(b *testing.B) {
+	db, err := openBenchDB(b)
+	if err != nil {
+		b.Fatalf("failed to open sqlite: %v", err)
+	}
+	defer db.Close()
+
+	s, err := litestore.NewStore[TestPersonWithKey](b.Context(), db, "bench_save")
+	if err != nil {
+		b.Fatalf("failed to create new store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := b.Context()
+	entity := &TestPersonWithKey{K: "bench", Name: "bench", Category: "A", Value: 1}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := s.Save(ctx, entity); err != nil {
+			b.Fatalf("failed to save entity: %v", err)
+		}
+	}
+}
+
+func BenchmarkStore_Iter(b *testing.B) {
+	db, err := openBenchDB(b)
+	if err != nil {
+		b.Fatalf("failed to open sqlite: %v", err)
+	}
+	defer db.Close()
+
+	s, err := litestore.NewStore[TestPersonWithKey](b.Context(), db, "bench_iter")
+	if err != nil {
+		b.Fatalf("failed to create new store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := b.Context()
+	for range 100 {
+		if err := s.Save(ctx, &TestPersonWithKey{Name: "bench", Category: "A", Value: 1}); err != nil {
+			b.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		seq, err := s.Iter(ctx, nil)
+		if err != nil {
+			b.Fatalf("Iter failed: %v", err)
+		}
+		for _, err := range seq {
+			if err != nil {
+				b.Fatalf("iteration failed: %v", err)
+			}
+		}
+	}
+}
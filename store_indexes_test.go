@@ -2,8 +2,11 @@ package litestore_test
 
 import (
 	"context"
+	"database/sql"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dir01/litestore"
 )
@@ -226,3 +229,420 @@ func TestRegularStoreStillWorks(t *testing.T) {
 		t.Errorf("unexpected retrieved email: got %s, want test@example.com", retrieved.Email)
 	}
 }
+
+// queryPlan returns the EXPLAIN QUERY PLAN details for a query.
+func queryPlan(t *testing.T, db *sql.DB, query string, args []any) string {
+	t.Helper()
+
+	rows, err := db.QueryContext(t.Context(), "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		t.Fatalf("failed to explain query: %v", err)
+	}
+	defer rows.Close()
+
+	var details []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			t.Fatalf("failed to scan query plan: %v", err)
+		}
+		details = append(details, detail)
+	}
+	return strings.Join(details, "\n")
+}
+
+func TestIndexIsUsedByQueries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var lastQuery string
+	var lastArgs []any
+	logger := func(query string, args []any, _ time.Duration, _ error) {
+		lastQuery, lastArgs = query, args
+	}
+
+	store, err := litestore.NewStore[IndexedEntity](ctx, db, "index_usage",
+		litestore.WithIndex("email"),
+		litestore.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to create store with indexes: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.Iter(ctx, &litestore.Query{Predicate: litestore.Filter{Key: "email", Op: litestore.OpEq, Value: "a@example.com"}}); err != nil {
+		t.Fatalf("Iter failed: %v", err)
+	}
+
+	plan := queryPlan(t, db, lastQuery, lastArgs)
+	if !strings.Contains(plan, "USING INDEX idx_index_usage_email") {
+		t.Errorf("expected query to use the email index, got plan:\n%s", plan)
+	}
+}
+
+func TestTimeIndexIsUsedByQueries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	type Event struct {
+		ID        string    `litestore:"key"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	var lastQuery string
+	var lastArgs []any
+	logger := func(query string, args []any, _ time.Duration, _ error) {
+		lastQuery, lastArgs = query, args
+	}
+
+	store, err := litestore.NewStore[Event](ctx, db, "time_index_usage",
+		litestore.WithIndex("created_at"),
+		litestore.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to create store with indexes: %v", err)
+	}
+	defer store.Close()
+
+	// Time filters and orders compare normalized timestamps, which the index is built on.
+	for name, q := range map[string]*litestore.Query{
+		"range filter": {Predicate: litestore.GTEFilter("created_at", time.Date(2023, 10, 27, 10, 0, 0, 0, time.UTC))},
+		"order":        {OrderBy: []litestore.OrderBy{{Key: "created_at", Direction: litestore.OrderDesc}}},
+	} {
+		if _, err := store.Iter(ctx, q); err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		plan := queryPlan(t, db, lastQuery, lastArgs)
+		if !strings.Contains(plan, "idx_time_index_usage_created_at") || strings.Contains(plan, "TEMP B-TREE") {
+			t.Errorf("expected the %s to use the created_at index, got plan:\n%s", name, plan)
+		}
+	}
+}
+
+func TestCollatedIndexIsUsedByQueries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var lastQuery string
+	var lastArgs []any
+	logger := func(query string, args []any, _ time.Duration, _ error) {
+		lastQuery, lastArgs = query, args
+	}
+
+	store, err := litestore.NewStore[IndexedEntity](ctx, db, "collated_index_usage",
+		litestore.WithCollation("NOCASE"),
+		litestore.WithIndex("email"),
+		litestore.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to create store with indexes: %v", err)
+	}
+	defer store.Close()
+
+	// Filters and orders compare the field with the store's collation, which the index is built with.
+	for name, q := range map[string]*litestore.Query{
+		"filter": {Predicate: litestore.EqFilter("email", "A@example.com")},
+		"order":  {OrderBy: []litestore.OrderBy{{Key: "email", Direction: litestore.OrderAsc}}},
+	} {
+		if _, err := store.Iter(ctx, q); err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		plan := queryPlan(t, db, lastQuery, lastArgs)
+		if !strings.Contains(plan, "USING INDEX idx_collated_index_usage_email") || strings.Contains(plan, "TEMP B-TREE") {
+			t.Errorf("expected the %s to use the email index, got plan:\n%s", name, plan)
+		}
+	}
+}
+
+func TestNestedIndexCreation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	type Address struct {
+		City string `json:"city"`
+	}
+	type Customer struct {
+		ID          string  `litestore:"key"`
+		Address     Address `json:"address"`
+		AddressCity string  `json:"address_city"`
+	}
+
+	var lastQuery string
+	var lastArgs []any
+	logger := func(query string, args []any, _ time.Duration, _ error) {
+		lastQuery, lastArgs = query, args
+	}
+
+	store, err := litestore.NewStore[Customer](ctx, db, "nested_index",
+		litestore.WithIndex("address.city"),
+		litestore.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to create store with nested index: %v", err)
+	}
+	defer store.Close()
+
+	var indexSQL string
+	err = db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'index' AND name = 'idx_nested_index_address_city'").Scan(&indexSQL)
+	if err != nil {
+		t.Fatalf("expected index idx_nested_index_address_city: %v", err)
+	}
+	if !strings.Contains(indexSQL, `json_extract("json", '$.address.city')`) {
+		t.Errorf("expected the index to be on the nested path, got %s", indexSQL)
+	}
+
+	if _, err := store.Iter(ctx, &litestore.Query{Predicate: litestore.EqFilter("address.city", "Oslo")}); err != nil {
+		t.Fatalf("Iter failed: %v", err)
+	}
+	plan := queryPlan(t, db, lastQuery, lastArgs)
+	if !strings.Contains(plan, "USING INDEX idx_nested_index_address_city") {
+		t.Errorf("expected query to use the nested index, got plan:\n%s", plan)
+	}
+
+	t.Run("invalid nested path", func(t *testing.T) {
+		_, err := litestore.NewStore[Customer](ctx, db, "nested_index_invalid", litestore.WithIndex("address.street"))
+		if err == nil {
+			t.Fatal("expected an error for an unknown nested field, got nil")
+		}
+	})
+
+	t.Run("index names must not collide", func(t *testing.T) {
+		_, err := litestore.NewStore[Customer](ctx, db, "nested_index_collision",
+			litestore.WithIndex("address.city"),
+			litestore.WithIndex("address_city"))
+		if err == nil {
+			t.Fatal("expected an error for fields sharing an index name, got nil")
+		}
+	})
+}
+
+func TestPartialIndexCreation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store, err := litestore.NewStore[IndexedEntity](ctx, db, "partial_indexed",
+		litestore.WithPartialIndex("email", litestore.Filter{Key: "category", Op: litestore.OpNEq, Value: "it's archived"}))
+	if err != nil {
+		t.Fatalf("failed to create store with partial index: %v", err)
+	}
+	defer store.Close()
+
+	var indexSQL string
+	err = db.QueryRowContext(ctx, `
+		SELECT sql FROM sqlite_master
+		WHERE type='index' AND name='idx_partial_indexed_email_partial'`).Scan(&indexSQL)
+	if err != nil {
+		t.Fatalf("failed to find partial index: %v", err)
+	}
+
+	expectedWhere := `WHERE json_extract("json", '$.category') != 'it''s archived'`
+	if !strings.HasSuffix(indexSQL, expectedWhere) {
+		t.Errorf("unexpected partial index definition: %s", indexSQL)
+	}
+
+	// The store keeps working with the partial index in place.
+	if err := store.Save(ctx, &IndexedEntity{Email: "a@example.com", Category: "active"}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+	if _, err := store.GetOne(ctx, litestore.Filter{Key: "email", Op: litestore.OpEq, Value: "a@example.com"}); err != nil {
+		t.Fatalf("failed to get entity: %v", err)
+	}
+
+	t.Run("invalid condition key returns error", func(t *testing.T) {
+		_, err := litestore.NewStore[IndexedEntity](ctx, db, "partial_invalid",
+			litestore.WithPartialIndex("email", litestore.Filter{Key: "nonexistent", Op: litestore.OpEq, Value: 1}))
+		if err == nil {
+			t.Fatal("expected an error for invalid condition key, got nil")
+		}
+	})
+}
+
+func TestSparseIndexCreation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Customer omits an empty email from the stored document.
+	store, err := litestore.NewStore[Customer](ctx, db, "sparse_indexed", litestore.WithSparseIndex("email"))
+	if err != nil {
+		t.Fatalf("failed to create store with sparse index: %v", err)
+	}
+	defer store.Close()
+
+	var indexSQL string
+	err = db.QueryRowContext(ctx, `
+		SELECT sql FROM sqlite_master
+		WHERE type='index' AND name='idx_sparse_indexed_email_sparse'`).Scan(&indexSQL)
+	if err != nil {
+		t.Fatalf("failed to find sparse index: %v", err)
+	}
+	if !strings.HasSuffix(indexSQL, `WHERE json_extract("json", '$.email') IS NOT NULL`) {
+		t.Errorf("unexpected sparse index definition: %s", indexSQL)
+	}
+
+	for _, c := range []*Customer{{Name: "alice", Email: "a@example.com"}, {Name: "bob"}} {
+		if err := store.Save(ctx, c); err != nil {
+			t.Fatalf("failed to save customer: %v", err)
+		}
+	}
+
+	var indexed int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sparse_indexed INDEXED BY idx_sparse_indexed_email_sparse WHERE json_extract(json, '$.email') IS NOT NULL`).Scan(&indexed)
+	if err != nil {
+		t.Fatalf("failed to count indexed rows: %v", err)
+	}
+	if indexed != 1 {
+		t.Errorf("expected only the row with an email to be indexed, got %d", indexed)
+	}
+
+	for _, p := range []litestore.Predicate{
+		litestore.EqFilter("email", "a@example.com"),
+		litestore.InFilter("email", "a@example.com", "b@example.com"),
+	} {
+		query, args, err := store.CompileQuery(&litestore.Query{Predicate: p})
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		if plan := queryPlan(t, db, query, args); !strings.Contains(plan, "USING INDEX idx_sparse_indexed_email_sparse") {
+			t.Errorf("expected query to use the sparse index, got plan:\n%s", plan)
+		}
+	}
+}
+
+func TestQueryIndexedBy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store, err := litestore.NewStore[IndexedEntity](ctx, db, "indexed_by", litestore.WithIndex("email"), litestore.WithIndex("category"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Save(ctx, &IndexedEntity{Email: "a@example.com", Category: "active"}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+
+	t.Run("named index is used", func(t *testing.T) {
+		q := &litestore.Query{
+			Predicate: litestore.AndPredicates(litestore.EqFilter("email", "a@example.com"), litestore.EqFilter("category", "active")),
+			IndexedBy: "idx_indexed_by_category",
+		}
+		query, args, err := store.CompileQuery(q)
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		if plan := queryPlan(t, db, query, args); !strings.Contains(plan, "USING INDEX idx_indexed_by_category") {
+			t.Errorf("expected query to use the category index, got plan:\n%s", plan)
+		}
+		if _, err := store.GetOneOrdered(ctx, q); err != nil {
+			t.Fatalf("failed to get entity: %v", err)
+		}
+	})
+
+	t.Run("unknown index is rejected", func(t *testing.T) {
+		_, _, err := store.CompileQuery(&litestore.Query{IndexedBy: "sqlite_autoindex_indexed_by_1"})
+		if err == nil {
+			t.Fatal("expected an error for an index not created by the store, got nil")
+		}
+	})
+}
+
+func TestListAndDropIndexes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// An earlier version of the schema indexed "name", which is no longer configured.
+	old, err := litestore.NewStore[IndexedEntity](ctx, db, "indexed_entities_lifecycle",
+		litestore.WithIndex("email"),
+		litestore.WithIndex("name"))
+	if err != nil {
+		t.Fatalf("failed to create store with indexes: %v", err)
+	}
+	defer old.Close()
+
+	store, err := litestore.NewStore[IndexedEntity](ctx, db, "indexed_entities_lifecycle",
+		litestore.WithIndex("email"))
+	if err != nil {
+		t.Fatalf("failed to create store with indexes: %v", err)
+	}
+	defer store.Close()
+
+	indexes, err := store.ListIndexes(ctx)
+	if err != nil {
+		t.Fatalf("ListIndexes failed: %v", err)
+	}
+	var names []string
+	var orphans []string
+	for _, idx := range indexes {
+		names = append(names, idx.Name)
+		if !idx.Configured {
+			orphans = append(orphans, idx.Name)
+		}
+		if !strings.HasPrefix(idx.SQL, "CREATE INDEX") {
+			t.Errorf("expected the CREATE INDEX statement of %s, got %q", idx.Name, idx.SQL)
+		}
+	}
+	if want := []string{"idx_indexed_entities_lifecycle_email", "idx_indexed_entities_lifecycle_name"}; !slices.Equal(names, want) {
+		t.Fatalf("expected indexes %v, got %v", want, names)
+	}
+	if want := []string{"idx_indexed_entities_lifecycle_name"}; !slices.Equal(orphans, want) {
+		t.Fatalf("expected orphaned indexes %v, got %v", want, orphans)
+	}
+
+	t.Run("drops an orphaned index", func(t *testing.T) {
+		if err := store.DropIndexByName(ctx, "idx_indexed_entities_lifecycle_name"); err != nil {
+			t.Fatalf("DropIndexByName failed: %v", err)
+		}
+		indexes, err := store.ListIndexes(ctx)
+		if err != nil {
+			t.Fatalf("ListIndexes failed: %v", err)
+		}
+		if len(indexes) != 1 || indexes[0].Name != "idx_indexed_entities_lifecycle_email" {
+			t.Errorf("expected only the email index to remain, got %+v", indexes)
+		}
+	})
+
+	t.Run("refuses a configured index", func(t *testing.T) {
+		if err := store.DropIndexByName(ctx, "idx_indexed_entities_lifecycle_email"); err == nil {
+			t.Error("expected an error for a configured index, got nil")
+		}
+	})
+
+	t.Run("refuses an unknown index", func(t *testing.T) {
+		if err := store.DropIndexByName(ctx, "idx_does_not_exist"); err == nil {
+			t.Error("expected an error for an unknown index, got nil")
+		}
+	})
+
+	t.Run("refuses an index of another table", func(t *testing.T) {
+		other, err := litestore.NewStore[IndexedEntity](ctx, db, "indexed_entities_other", litestore.WithIndex("name"))
+		if err != nil {
+			t.Fatalf("failed to create store with indexes: %v", err)
+		}
+		defer other.Close()
+		if err := store.DropIndexByName(ctx, "idx_indexed_entities_other_name"); err == nil {
+			t.Error("expected an error for an index on another table, got nil")
+		}
+	})
+}
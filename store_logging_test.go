@@ -0,0 +1,104 @@
+package litestore_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_WithLogger(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	type loggedQuery struct {
+		query string
+		args  []any
+		err   error
+	}
+	var logged []loggedQuery
+	logger := func(query string, args []any, duration time.Duration, err error) {
+		if duration < 0 {
+			t.Errorf("expected non-negative duration, got %v", duration)
+		}
+		logged = append(logged, loggedQuery{query: query, args: args, err: err})
+	}
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_logger", litestore.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	t.Run("save is logged", func(t *testing.T) {
+		logged = nil
+		if err := s.Save(ctx, &TestPersonWithKey{K: "logged", Name: "alice"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if len(logged) != 1 {
+			t.Fatalf("expected 1 logged query, got %d", len(logged))
+		}
+		if !strings.Contains(logged[0].query, `INSERT INTO "test_entities_logger"`) {
+			t.Errorf("unexpected query logged: %s", logged[0].query)
+		}
+		if len(logged[0].args) != 2 || logged[0].args[0] != "logged" {
+			t.Errorf("unexpected args logged: %v", logged[0].args)
+		}
+	})
+
+	t.Run("iter is logged", func(t *testing.T) {
+		logged = nil
+		seq, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "alice"}})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		for _, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+		}
+		if len(logged) != 1 {
+			t.Fatalf("expected 1 logged query, got %d", len(logged))
+		}
+		if !strings.Contains(logged[0].query, `SELECT "key", "json" FROM "test_entities_logger" WHERE`) {
+			t.Errorf("unexpected query logged: %s", logged[0].query)
+		}
+	})
+
+	t.Run("delete is logged", func(t *testing.T) {
+		logged = nil
+		if err := s.Delete(ctx, "logged"); err != nil {
+			t.Fatalf("failed to delete entity: %v", err)
+		}
+		if len(logged) != 1 {
+			t.Fatalf("expected 1 logged query, got %d", len(logged))
+		}
+		if !strings.Contains(logged[0].query, `DELETE FROM "test_entities_logger"`) {
+			t.Errorf("unexpected query logged: %s", logged[0].query)
+		}
+	})
+
+	t.Run("failed query is logged with its error", func(t *testing.T) {
+		logged = nil
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := s.Iter(ctx, nil)
+		if err == nil {
+			t.Fatal("expected an error for a cancelled context, got nil")
+		}
+		if len(logged) != 1 {
+			t.Fatalf("expected 1 logged query, got %d", len(logged))
+		}
+		if logged[0].err == nil {
+			t.Error("expected the logged query to carry an error")
+		}
+	})
+}
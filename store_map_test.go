@@ -0,0 +1,156 @@
+package litestore_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_MapEntities(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[map[string]any](ctx, db, "test_map_entities", litestore.WithIndex("kind"))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	docs := map[string]map[string]any{
+		"a": {"kind": "note", "title": "first", "priority": 2},
+		"b": {"kind": "note", "title": "second", "priority": 1, "tags": []any{"x"}},
+		"c": {"kind": "task", "done": true, "address": map[string]any{"city": "Paris"}},
+	}
+	for key, doc := range docs {
+		if err := s.SaveWithKey(ctx, key, &doc); err != nil {
+			t.Fatalf("SaveWithKey failed: %v", err)
+		}
+	}
+
+	t.Run("any key can be queried", func(t *testing.T) {
+		seq, err := s.Iter(ctx, &litestore.Query{
+			Predicate: litestore.EqFilter("kind", "note"),
+			OrderBy:   []litestore.OrderBy{{Key: "priority", Direction: litestore.OrderAsc}},
+		})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var titles []any
+		for doc, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			titles = append(titles, doc["title"])
+		}
+		if want := []any{"second", "first"}; !reflect.DeepEqual(titles, want) {
+			t.Errorf("expected %v, got %v", want, titles)
+		}
+
+		got, err := s.GetOne(ctx, litestore.EqFilter("address.city", "Paris"))
+		if err != nil {
+			t.Fatalf("GetOne failed: %v", err)
+		}
+		if got["kind"] != "task" || got["done"] != true {
+			t.Errorf("unexpected document: %v", got)
+		}
+	})
+
+	t.Run("SaveWithKey replaces the document", func(t *testing.T) {
+		doc := map[string]any{"kind": "note", "title": "first, edited"}
+		if err := s.SaveWithKey(ctx, "a", &doc); err != nil {
+			t.Fatalf("SaveWithKey failed: %v", err)
+		}
+		got, err := s.GetMany(ctx, []string{"a"})
+		if err != nil {
+			t.Fatalf("GetMany failed: %v", err)
+		}
+		if !reflect.DeepEqual(got["a"], doc) {
+			t.Errorf("expected %v, got %v", doc, got["a"])
+		}
+	})
+
+	t.Run("Save generates a key", func(t *testing.T) {
+		doc := map[string]any{"kind": "generated"}
+		if err := s.Save(ctx, &doc); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if _, err := s.GetOne(ctx, litestore.EqFilter("kind", "generated")); err != nil {
+			t.Errorf("GetOne failed: %v", err)
+		}
+	})
+
+	t.Run("empty key is rejected", func(t *testing.T) {
+		doc := map[string]any{"kind": "note"}
+		if err := s.SaveWithKey(ctx, "", &doc); err == nil {
+			t.Error("expected an error for an empty key, got nil")
+		}
+	})
+
+	t.Run("typed map values", func(t *testing.T) {
+		counts, err := litestore.NewStore[map[string]int](ctx, db, "test_map_counts")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := counts.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		doc := map[string]int{"apples": 3, "pears": 5}
+		if err := counts.SaveWithKey(ctx, "basket", &doc); err != nil {
+			t.Fatalf("SaveWithKey failed: %v", err)
+		}
+		got, err := counts.GetOne(ctx, litestore.GTFilter("pears", 4))
+		if err != nil {
+			t.Fatalf("GetOne failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, doc) {
+			t.Errorf("expected %v, got %v", doc, got)
+		}
+	})
+
+	t.Run("maps without string keys are rejected", func(t *testing.T) {
+		if _, err := litestore.NewStore[map[int]string](ctx, db, "test_map_int_keys"); err == nil {
+			t.Error("expected an error for a map with int keys, got nil")
+		}
+	})
+}
+
+func TestStore_SaveWithKey_KeyField(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_save_with_key")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	p := &TestPersonWithKey{Name: "alice"}
+	if err := s.SaveWithKey(ctx, "alice-key", p); err != nil {
+		t.Fatalf("SaveWithKey failed: %v", err)
+	}
+	if p.K != "alice-key" {
+		t.Errorf("expected the key field to be set, got %q", p.K)
+	}
+	got, err := s.GetOne(ctx, litestore.EqFilter("k", "alice-key"))
+	if err != nil {
+		t.Fatalf("GetOne failed: %v", err)
+	}
+	if got.Name != "alice" {
+		t.Errorf("expected alice, got %+v", got)
+	}
+}
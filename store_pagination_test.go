@@ -0,0 +1,300 @@
+package litestore_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_Paginate(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_paginate")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	// Setup data: keys are zero-padded so that their lexical order matches insertion order.
+	var allKeys, activeKeys []string
+	for i := range 7 {
+		e := &TestPersonWithKey{K: fmt.Sprintf("key-%02d", i), Name: fmt.Sprintf("person-%d", i), IsActive: i%2 == 0}
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		allKeys = append(allKeys, e.K)
+		if e.IsActive {
+			activeKeys = append(activeKeys, e.K)
+		}
+	}
+
+	collect := func(t *testing.T, p litestore.Predicate, limit int) ([]string, int) {
+		t.Helper()
+		var keys []string
+		pages := 0
+		cursor := ""
+		for {
+			page, next, err := s.Paginate(ctx, p, cursor, limit)
+			if err != nil {
+				t.Fatalf("Paginate failed: %v", err)
+			}
+			if len(page) > limit {
+				t.Fatalf("page has %d items, limit is %d", len(page), limit)
+			}
+			pages++
+			for _, e := range page {
+				keys = append(keys, e.K)
+			}
+			if next == "" {
+				break
+			}
+			if next != page[len(page)-1].K {
+				t.Fatalf("expected cursor to be the last key of the page %q, got %q", page[len(page)-1].K, next)
+			}
+			cursor = next
+		}
+		return keys, pages
+	}
+
+	t.Run("walks all entities in key order", func(t *testing.T) {
+		keys, pages := collect(t, nil, 3)
+		if !slices.Equal(keys, allKeys) {
+			t.Errorf("unexpected keys: got %v, want %v", keys, allKeys)
+		}
+		if pages != 3 {
+			t.Errorf("expected 3 pages, got %d", pages)
+		}
+	})
+
+	t.Run("exact multiple of limit has no trailing empty page", func(t *testing.T) {
+		keys, pages := collect(t, litestore.Filter{Key: "is_active", Op: litestore.OpEq, Value: true}, 2)
+		if !slices.Equal(keys, activeKeys) {
+			t.Errorf("unexpected keys: got %v, want %v", keys, activeKeys)
+		}
+		if pages != 2 {
+			t.Errorf("expected 2 pages, got %d", pages)
+		}
+	})
+
+	t.Run("cursor past the end returns empty page", func(t *testing.T) {
+		page, next, err := s.Paginate(ctx, nil, "zzz", 10)
+		if err != nil {
+			t.Fatalf("Paginate failed: %v", err)
+		}
+		if len(page) != 0 || next != "" {
+			t.Errorf("expected empty page and cursor, got %d items and cursor %q", len(page), next)
+		}
+	})
+
+	t.Run("invalid limit returns error", func(t *testing.T) {
+		_, _, err := s.Paginate(ctx, nil, "", 0)
+		if err == nil {
+			t.Fatal("expected an error for zero limit, got nil")
+		}
+	})
+}
+
+func TestStore_Paginate_NoKey(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonNoKey](t.Context(), db, "test_entities_paginate_nokey")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	for i := range 5 {
+		if err := s.Save(ctx, &TestPersonNoKey{Info: "info", Data: i}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	seen := make(map[int]bool)
+	cursor := ""
+	for {
+		page, next, err := s.Paginate(ctx, nil, cursor, 2)
+		if err != nil {
+			t.Fatalf("Paginate failed: %v", err)
+		}
+		for _, e := range page {
+			if seen[e.Data] {
+				t.Fatalf("entity %d returned twice", e.Data)
+			}
+			seen[e.Data] = true
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if len(seen) != 5 {
+		t.Errorf("expected to see 5 entities, got %d", len(seen))
+	}
+}
+
+func TestStore_Page(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_page")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	for i := range 7 {
+		e := &TestPersonWithKey{K: fmt.Sprintf("key-%02d", i), Name: fmt.Sprintf("person-%d", i), IsActive: i%2 == 0, Value: i}
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	t.Run("total ignores limit", func(t *testing.T) {
+		items, total, err := s.Page(ctx, &litestore.Query{
+			Predicate: litestore.Filter{Key: "is_active", Op: litestore.OpEq, Value: true},
+			OrderBy:   []litestore.OrderBy{{Key: "value", Direction: litestore.OrderDesc}},
+			Limit:     2,
+		})
+		if err != nil {
+			t.Fatalf("Page failed: %v", err)
+		}
+		if total != 4 {
+			t.Errorf("expected total of 4, got %d", total)
+		}
+		var keys []string
+		for _, e := range items {
+			keys = append(keys, e.K)
+		}
+		if expected := []string{"key-06", "key-04"}; !slices.Equal(keys, expected) {
+			t.Errorf("unexpected keys: got %v, want %v", keys, expected)
+		}
+	})
+
+	t.Run("nil query returns everything", func(t *testing.T) {
+		items, total, err := s.Page(ctx, nil)
+		if err != nil {
+			t.Fatalf("Page failed: %v", err)
+		}
+		if total != 7 || len(items) != 7 {
+			t.Errorf("expected 7 items and total 7, got %d items and total %d", len(items), total)
+		}
+	})
+
+	t.Run("invalid query returns error", func(t *testing.T) {
+		_, _, err := s.Page(ctx, &litestore.Query{Predicate: litestore.Filter{Key: "nope", Op: litestore.OpEq, Value: 1}})
+		if err == nil {
+			t.Fatal("expected an error for an invalid key, got nil")
+		}
+	})
+}
+
+func TestStore_IterBatched(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// With a single connection, writing while a plain Iter is open would block.
+	db.SetMaxOpenConns(1)
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_iter_batched")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	var allKeys, activeKeys []string
+	for i := range 7 {
+		e := &TestPersonWithKey{K: fmt.Sprintf("key-%02d", i), Name: fmt.Sprintf("person-%d", i), IsActive: i%2 == 0}
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		allKeys = append(allKeys, e.K)
+		if e.IsActive {
+			activeKeys = append(activeKeys, e.K)
+		}
+	}
+
+	collect := func(t *testing.T, p litestore.Predicate, batchSize int) []string {
+		t.Helper()
+		seq, err := s.IterBatched(ctx, p, batchSize)
+		if err != nil {
+			t.Fatalf("IterBatched failed: %v", err)
+		}
+		var keys []string
+		for entity, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			keys = append(keys, entity.K)
+		}
+		return keys
+	}
+
+	t.Run("yields every entity in key order", func(t *testing.T) {
+		for _, batchSize := range []int{1, 3, 7, 10} {
+			if got := collect(t, nil, batchSize); !slices.Equal(got, allKeys) {
+				t.Errorf("batch size %d: expected %v, got %v", batchSize, allKeys, got)
+			}
+		}
+	})
+
+	t.Run("filters by predicate", func(t *testing.T) {
+		p := litestore.Filter{Key: "is_active", Op: litestore.OpEq, Value: true}
+		if got := collect(t, p, 2); !slices.Equal(got, activeKeys) {
+			t.Errorf("expected %v, got %v", activeKeys, got)
+		}
+	})
+
+	t.Run("connection is released between batches", func(t *testing.T) {
+		seq, err := s.IterBatched(ctx, nil, 2)
+		if err != nil {
+			t.Fatalf("IterBatched failed: %v", err)
+		}
+		for entity, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			entity.Value++
+			if err := s.Save(ctx, &entity); err != nil {
+				t.Fatalf("failed to save entity during iteration: %v", err)
+			}
+		}
+	})
+
+	t.Run("invalid batch size", func(t *testing.T) {
+		if _, err := s.IterBatched(ctx, nil, 0); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
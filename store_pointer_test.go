@@ -0,0 +1,76 @@
+package litestore_test
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_PointerType(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[*TestPersonWithKey](t.Context(), db, "test_entities_pointer", litestore.WithIndex("name"))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	t.Run("save sets key and get returns pointer", func(t *testing.T) {
+		entity := &TestPersonWithKey{Name: "alice", Category: "A", Value: 10}
+		if err := s.Save(ctx, &entity); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if entity.K == "" {
+			t.Fatal("expected key to be populated by Save, but it's empty")
+		}
+
+		got, err := s.GetOne(ctx, litestore.Filter{Key: "k", Op: litestore.OpEq, Value: entity.K})
+		if err != nil {
+			t.Fatalf("failed to get entity back: %v", err)
+		}
+		if got == nil {
+			t.Fatal("expected a non-nil entity")
+		}
+		if !reflect.DeepEqual(*got, *entity) {
+			t.Errorf("retrieved entity does not match saved one.\ngot:  %+v\nwant: %+v", *got, *entity)
+		}
+	})
+
+	t.Run("miss returns nil", func(t *testing.T) {
+		got, err := s.GetOne(ctx, litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "nobody"})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+		if got != nil {
+			t.Errorf("expected nil entity on miss, got %+v", got)
+		}
+	})
+
+	t.Run("saving a nil pointer fails", func(t *testing.T) {
+		var entity *TestPersonWithKey
+		if err := s.Save(ctx, &entity); err == nil {
+			t.Fatal("expected an error when saving a nil pointer, got nil")
+		}
+	})
+
+	t.Run("pointer to non-struct is rejected", func(t *testing.T) {
+		_, err := litestore.NewStore[*int](ctx, db, "test_entities_pointer_int")
+		if err == nil {
+			t.Fatal("expected an error for pointer to non-struct, got nil")
+		}
+		expectedErr := "type T must be a struct or a pointer to a struct, but got *int"
+		if err.Error() != expectedErr {
+			t.Fatalf("expected error '%s', got '%s'", expectedErr, err.Error())
+		}
+	})
+}
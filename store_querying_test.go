@@ -1,10 +1,18 @@
 package litestore_test
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dir01/litestore"
 )
@@ -188,6 +196,67 @@ func TestStore_Querying_Iter(t *testing.T) {
 		}
 	})
 
+	t.Run("query with random order", func(t *testing.T) {
+		q := &litestore.Query{
+			Predicate:   litestore.Filter{Key: "category", Op: litestore.OpEq, Value: "B"}, // charlie, david
+			RandomOrder: true,
+			Limit:       1,
+		}
+		seen := make(map[string]bool)
+		for range 50 {
+			seq, err := s.Iter(ctx, q)
+			if err != nil {
+				t.Fatalf("Iter failed: %v", err)
+			}
+			for entity, err := range seq {
+				if err != nil {
+					t.Fatalf("iteration failed: %v", err)
+				}
+				seen[entity.Name] = true
+			}
+		}
+		if len(seen) != 2 || !seen["charlie"] || !seen["david"] {
+			t.Errorf("expected both charlie and david to be sampled, got %v", seen)
+		}
+
+		// Random order only breaks ties of an explicit order.
+		q = &litestore.Query{
+			OrderBy:     []litestore.OrderBy{{Key: "value", Direction: litestore.OrderDesc}},
+			RandomOrder: true,
+			Limit:       1,
+		}
+		seq, err := s.Iter(ctx, q)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		for entity, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			if entity.Name != "bob" {
+				t.Errorf("expected bob, got %s", entity.Name)
+			}
+		}
+	})
+
+	t.Run("random single entity", func(t *testing.T) {
+		seen := make(map[string]bool)
+		for range 50 {
+			entity, err := s.Random(ctx, litestore.EqFilter("category", "B")) // charlie, david
+			if err != nil {
+				t.Fatalf("Random failed: %v", err)
+			}
+			seen[entity.Name] = true
+		}
+		if len(seen) != 2 || !seen["charlie"] || !seen["david"] {
+			t.Errorf("expected both charlie and david to be picked, got %v", seen)
+		}
+
+		if _, err := s.Random(ctx, litestore.EqFilter("category", "Z")); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
 	t.Run("query with order by key", func(t *testing.T) {
 		// get all entities and sort by ID descending
 		var ids []string
@@ -281,6 +350,32 @@ func TestStore_Querying_ErrorCases(t *testing.T) {
 			t.Errorf("wrong error message. \ngot: %s\nwant: %s", err.Error(), expectedErr)
 		}
 	})
+
+	t.Run("query with empty AND returns error", func(t *testing.T) {
+		_, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.AndPredicates()})
+		if err == nil {
+			t.Fatal("expected error for empty AND predicate, but got nil")
+		}
+		expectedErr := "building query: empty AND predicate: at least one predicate is required"
+		if err.Error() != expectedErr {
+			t.Errorf("wrong error message. \ngot: %s\nwant: %s", err.Error(), expectedErr)
+		}
+	})
+
+	t.Run("query with nested empty OR returns error", func(t *testing.T) {
+		p := litestore.AndPredicates(
+			litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "alice"},
+			litestore.OrPredicates(),
+		)
+		_, err := s.Iter(ctx, &litestore.Query{Predicate: p})
+		if err == nil {
+			t.Fatal("expected error for empty OR predicate, but got nil")
+		}
+		expectedErr := "building query: empty OR predicate: at least one predicate is required"
+		if err.Error() != expectedErr {
+			t.Errorf("wrong error message. \ngot: %s\nwant: %s", err.Error(), expectedErr)
+		}
+	})
 }
 
 func TestStore_Querying_FilterOperators(t *testing.T) {
@@ -390,9 +485,9 @@ func TestStore_Querying_InNotInOperators(t *testing.T) {
 
 	// Setup test data
 	testEntities := []*TestPersonWithKey{
-		{Name: "alice", Category: "A", Value: 10},
+		{Name: "alice", Category: "A", IsActive: true, Value: 10},
 		{Name: "bob", Category: "B", Value: 20},
-		{Name: "charlie", Category: "C", Value: 30},
+		{Name: "charlie", Category: "C", IsActive: true, Value: 30},
 		{Name: "david", Category: "A", Value: 40},
 	}
 
@@ -461,6 +556,35 @@ func TestStore_Querying_InNotInOperators(t *testing.T) {
 		}
 	})
 
+	t.Run("OpIn with []bool", func(t *testing.T) {
+		for _, tt := range []struct {
+			op       litestore.Operator
+			expected []string
+		}{
+			{litestore.OpIn, []string{"alice", "charlie"}},
+			{litestore.OpNotIn, []string{"bob", "david"}},
+		} {
+			q := &litestore.Query{Predicate: litestore.Filter{Key: "is_active", Op: tt.op, Value: []bool{true}}}
+			seq, err := s.Iter(ctx, q)
+			if err != nil {
+				t.Fatalf("Iter failed: %v", err)
+			}
+
+			var resultNames []string
+			for entity, err := range seq {
+				if err != nil {
+					t.Fatalf("iteration failed: %v", err)
+				}
+				resultNames = append(resultNames, entity.Name)
+			}
+
+			sort.Strings(resultNames)
+			if !reflect.DeepEqual(resultNames, tt.expected) {
+				t.Errorf("%s: expected names %v, got %v", tt.op, tt.expected, resultNames)
+			}
+		}
+	})
+
 	t.Run("OpNotIn with []string", func(t *testing.T) {
 		filter := litestore.Filter{
 			Key:   "category",
@@ -544,6 +668,54 @@ func TestStore_Querying_InNotInOperators(t *testing.T) {
 		}
 	})
 
+	t.Run("empty lists on the key field and from constructors", func(t *testing.T) {
+		var none []string // e.g. a list built dynamically from an empty input
+		tests := []struct {
+			name     string
+			filter   litestore.Filter
+			expected []string
+		}{
+			{"OpIn on key field", litestore.Filter{Key: "k", Op: litestore.OpIn, Value: []string{}}, nil},
+			{"OpNotIn on key field", litestore.Filter{Key: "k", Op: litestore.OpNotIn, Value: []string{}}, []string{"alice", "bob", "charlie", "david"}},
+			{"InFilter with a nil list", litestore.InFilter("category", none...), nil},
+			{"NotInFilter with a nil list", litestore.NotInFilter("category", none...), []string{"alice", "bob", "charlie", "david"}},
+			{"empty array", litestore.Filter{Key: "category", Op: litestore.OpIn, Value: [0]string{}}, nil},
+		}
+		for _, tt := range tests {
+			seq, err := s.Iter(ctx, &litestore.Query{Predicate: tt.filter})
+			if err != nil {
+				t.Fatalf("%s: Iter failed: %v", tt.name, err)
+			}
+			var resultNames []string
+			for entity, err := range seq {
+				if err != nil {
+					t.Fatalf("%s: iteration failed: %v", tt.name, err)
+				}
+				resultNames = append(resultNames, entity.Name)
+			}
+			sort.Strings(resultNames)
+			if !reflect.DeepEqual(resultNames, tt.expected) {
+				t.Errorf("%s: expected names %v, got %v", tt.name, tt.expected, resultNames)
+			}
+		}
+	})
+
+	t.Run("nil slice is rejected", func(t *testing.T) {
+		for _, op := range []litestore.Operator{litestore.OpIn, litestore.OpNotIn} {
+			filter := litestore.Filter{Key: "category", Op: op, Value: []string(nil)}
+			if _, err := s.Iter(ctx, &litestore.Query{Predicate: filter}); err == nil {
+				t.Errorf("%s: expected an error for a nil slice, got nil", op)
+			}
+		}
+	})
+
+	t.Run("empty list still validates the key", func(t *testing.T) {
+		filter := litestore.Filter{Key: "nope", Op: litestore.OpIn, Value: []string{}}
+		if _, err := s.Iter(ctx, &litestore.Query{Predicate: filter}); err == nil {
+			t.Error("expected an error for an invalid key, got nil")
+		}
+	})
+
 	t.Run("OpIn with single element", func(t *testing.T) {
 		filter := litestore.Filter{
 			Key:   "name",
@@ -632,3 +804,1495 @@ func TestStore_Querying_InNotInOperators(t *testing.T) {
 		}
 	})
 }
+
+func TestStore_Querying_IterAs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_iter_as")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	for _, e := range []*TestPersonWithKey{
+		{Name: "alice", Category: "A", Value: 10},
+		{Name: "bob", Category: "A", Value: 20},
+		{Name: "charlie", Category: "B", Value: 30},
+	} {
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	type PersonView struct {
+		K    string `json:"k"`
+		Name string `json:"name"`
+	}
+
+	q := &litestore.Query{
+		Predicate: litestore.Filter{Key: "category", Op: litestore.OpEq, Value: "A"},
+		OrderBy:   []litestore.OrderBy{{Key: "value", Direction: litestore.OrderAsc}},
+	}
+	seq, err := litestore.IterAs[PersonView](ctx, s, q)
+	if err != nil {
+		t.Fatalf("IterAs failed: %v", err)
+	}
+
+	var names []string
+	for view, err := range seq {
+		if err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+		if view.K == "" {
+			t.Errorf("expected view to carry the key from the stored document, got empty for %s", view.Name)
+		}
+		names = append(names, view.Name)
+	}
+
+	expected := []string{"alice", "bob"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("unexpected names: got %v, want %v", names, expected)
+	}
+}
+
+func TestStore_Querying_TimeValues(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	type Event struct {
+		ID        string    `litestore:"key"`
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	s, err := litestore.NewStore[Event](t.Context(), db, "test_entities_time")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	plusTwo := time.FixedZone("UTC+2", 2*60*60)
+	minusFive := time.FixedZone("UTC-5", -5*60*60)
+	base := time.Date(2023, 10, 27, 10, 0, 0, 0, time.UTC)
+
+	// The same ordering of instants, but written in different locations.
+	for _, e := range []*Event{
+		{Name: "early", CreatedAt: base.Add(-time.Hour).In(plusTwo)},
+		{Name: "exact", CreatedAt: base.In(minusFive)},
+		{Name: "late", CreatedAt: base.Add(time.Hour)},
+	} {
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	queryNames := func(t *testing.T, p litestore.Predicate) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, &litestore.Query{Predicate: p})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var names []string
+		for e, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			names = append(names, e.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		op       litestore.Operator
+		value    time.Time
+		expected []string
+	}{
+		{"equal in another location", litestore.OpEq, base.In(plusTwo), []string{"exact"}},
+		{"greater or equal", litestore.OpGTE, base.In(minusFive), []string{"exact", "late"}},
+		{"less than", litestore.OpLT, base, []string{"early"}},
+		{"not equal", litestore.OpNEq, base.In(plusTwo), []string{"early", "late"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := queryNames(t, litestore.Filter{Key: "created_at", Op: tt.op, Value: tt.value})
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("unexpected results: got %v, want %v", names, tt.expected)
+			}
+		})
+	}
+
+	t.Run("in and not in across locations", func(t *testing.T) {
+		values := []time.Time{base.In(plusTwo), base.Add(time.Hour).In(minusFive)}
+
+		names := queryNames(t, litestore.Filter{Key: "created_at", Op: litestore.OpIn, Value: values})
+		if expected := []string{"exact", "late"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("unexpected IN results: got %v, want %v", names, expected)
+		}
+
+		names = queryNames(t, litestore.Filter{Key: "created_at", Op: litestore.OpNotIn, Value: values})
+		if expected := []string{"early"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("unexpected NOT IN results: got %v, want %v", names, expected)
+		}
+	})
+
+	t.Run("ordered as instants across locations", func(t *testing.T) {
+		seq, err := s.Iter(ctx, &litestore.Query{OrderBy: []litestore.OrderBy{{Key: "created_at", Direction: litestore.OrderAsc}}})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var names []string
+		for e, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			names = append(names, e.Name)
+		}
+		if expected := []string{"early", "exact", "late"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("unexpected order: got %v, want %v", names, expected)
+		}
+	})
+}
+
+func TestStore_Querying_IterRaw(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_iter_raw")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	if err := s.Save(ctx, &TestPersonWithKey{K: "good", Name: "alice", Value: 10}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+	// A document that no longer matches the entity type: "value" is a string.
+	if _, err := db.ExecContext(ctx, `INSERT INTO test_entities_iter_raw (key, json) VALUES ('drifted', '{"name": "bob", "value": "ten"}')`); err != nil {
+		t.Fatalf("failed to insert drifted row: %v", err)
+	}
+
+	seq, err := s.IterRaw(ctx, &litestore.Query{OrderBy: []litestore.OrderBy{{Key: "k", Direction: litestore.OrderAsc}}})
+	if err != nil {
+		t.Fatalf("IterRaw failed: %v", err)
+	}
+
+	got := make(map[string]map[string]any)
+	for pair, err := range seq {
+		if err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(pair.Value, &doc); err != nil {
+			t.Fatalf("raw value is not valid json: %v", err)
+		}
+		got[pair.Key] = doc
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 raw documents, got %d", len(got))
+	}
+	if got["good"]["name"] != "alice" {
+		t.Errorf("unexpected document for key 'good': %v", got["good"])
+	}
+	if got["drifted"]["value"] != "ten" {
+		t.Errorf("unexpected document for key 'drifted': %v", got["drifted"])
+	}
+}
+
+func TestStore_Querying_SkipUnmarshalErrors(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type badRow struct {
+		key string
+		raw string
+	}
+	var skipped []badRow
+	handler := func(key string, raw []byte, err error) {
+		if err == nil {
+			t.Errorf("expected an unmarshal error for key %s", key)
+		}
+		skipped = append(skipped, badRow{key: key, raw: string(raw)})
+	}
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_skip_errors", litestore.WithSkipUnmarshalErrors(handler))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, e := range []*TestPersonWithKey{{K: "a", Name: "alice"}, {K: "c", Name: "charlie"}} {
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+	const poison = `{"name": "bob", "value": "not a number"}`
+	if _, err := db.ExecContext(ctx, `INSERT INTO test_entities_skip_errors (key, json) VALUES ('b', ?)`, poison); err != nil {
+		t.Fatalf("failed to insert poison row: %v", err)
+	}
+
+	t.Run("iter skips and reports bad rows", func(t *testing.T) {
+		skipped = nil
+		seq, err := s.Iter(ctx, &litestore.Query{OrderBy: []litestore.OrderBy{{Key: "k", Direction: litestore.OrderAsc}}})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var names []string
+		for e, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			names = append(names, e.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"alice", "charlie"}) {
+			t.Errorf("unexpected names: %v", names)
+		}
+		if len(skipped) != 1 || skipped[0].key != "b" || skipped[0].raw != poison {
+			t.Errorf("unexpected skipped rows: %+v", skipped)
+		}
+	})
+
+	t.Run("paginate skips bad rows but keeps the cursor moving", func(t *testing.T) {
+		skipped = nil
+		page, next, err := s.Paginate(ctx, nil, "a", 1)
+		if err != nil {
+			t.Fatalf("Paginate failed: %v", err)
+		}
+		if len(page) != 0 || next != "b" {
+			t.Fatalf("expected empty page with cursor 'b', got %d items and cursor %q", len(page), next)
+		}
+		page, _, err = s.Paginate(ctx, nil, next, 1)
+		if err != nil {
+			t.Fatalf("Paginate failed: %v", err)
+		}
+		if len(page) != 1 || page[0].Name != "charlie" {
+			t.Errorf("unexpected page: %+v", page)
+		}
+		if len(skipped) != 1 {
+			t.Errorf("expected 1 skipped row, got %d", len(skipped))
+		}
+	})
+
+	t.Run("without the option bad rows fail the read", func(t *testing.T) {
+		strict, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_skip_errors")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := strict.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		_, err = strict.GetOne(ctx, litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "bob"})
+		if err == nil {
+			t.Fatal("expected an unmarshal error, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_CompileQuery(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_compile")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	tests := []struct {
+		name         string
+		query        *litestore.Query
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			name:         "nil query",
+			query:        nil,
+			expectedSQL:  `SELECT "key", "json" FROM "test_entities_compile"`,
+			expectedArgs: []any{},
+		},
+		{
+			name: "filters, order and limit",
+			query: &litestore.Query{
+				Predicate: litestore.AndPredicates(
+					litestore.Filter{Key: "k", Op: litestore.OpEq, Value: "abc"},
+					litestore.Filter{Key: "value", Op: litestore.OpGT, Value: 10},
+				),
+				OrderBy: []litestore.OrderBy{{Key: "name", Direction: litestore.OrderDesc}},
+				Limit:   5,
+			},
+			expectedSQL:  `SELECT "key", "json" FROM "test_entities_compile" WHERE ("key" = ?) AND (json_extract("json", '$.value') > ?) ORDER BY json_extract("json", '$.name') DESC LIMIT ?`,
+			expectedArgs: []any{"abc", int64(10), 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := s.CompileQuery(tt.query)
+			if err != nil {
+				t.Fatalf("CompileQuery failed: %v", err)
+			}
+			if query != tt.expectedSQL {
+				t.Errorf("unexpected SQL:\ngot:  %s\nwant: %s", query, tt.expectedSQL)
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("unexpected args: got %v, want %v", args, tt.expectedArgs)
+			}
+		})
+	}
+
+	t.Run("invalid query returns error", func(t *testing.T) {
+		_, _, err := s.CompileQuery(&litestore.Query{Predicate: litestore.Filter{Key: "nope", Op: litestore.OpEq, Value: 1}})
+		if err == nil {
+			t.Fatal("expected an error for an invalid key, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_Eq(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_eq")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	for _, e := range []*TestPersonWithKey{
+		{Name: "alice", Category: "A", IsActive: true},
+		{Name: "bob", Category: "A", IsActive: false},
+		{Name: "charlie", Category: "B", IsActive: true},
+	} {
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	p := litestore.Eq(map[string]any{"is_active": true, "category": "A"})
+
+	got, err := s.GetOne(ctx, p)
+	if err != nil {
+		t.Fatalf("GetOne failed: %v", err)
+	}
+	if got.Name != "alice" {
+		t.Errorf("expected alice, got %s", got.Name)
+	}
+
+	t.Run("generated SQL is stable", func(t *testing.T) {
+		first, _, err := s.CompileQuery(&litestore.Query{Predicate: p})
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		for range 10 {
+			again, _, err := s.CompileQuery(&litestore.Query{Predicate: litestore.Eq(map[string]any{"category": "A", "is_active": true})})
+			if err != nil {
+				t.Fatalf("CompileQuery failed: %v", err)
+			}
+			if again != first {
+				t.Fatalf("SQL is not stable:\n%s\n%s", first, again)
+			}
+		}
+		if !strings.Contains(first, `'$.category') = ?) AND (json_extract("json", '$.is_active') = ?)`) {
+			t.Errorf("expected filters ordered by key, got %s", first)
+		}
+	})
+
+	t.Run("empty map returns error", func(t *testing.T) {
+		if _, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.Eq(nil)}); err == nil {
+			t.Fatal("expected an error for an empty map, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_FilterConstructors(t *testing.T) {
+	tests := []struct {
+		name     string
+		got      litestore.Filter
+		expected litestore.Filter
+	}{
+		{"EqFilter", litestore.EqFilter("name", "alice"), litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "alice"}},
+		{"NEqFilter", litestore.NEqFilter("name", "alice"), litestore.Filter{Key: "name", Op: litestore.OpNEq, Value: "alice"}},
+		{"GTFilter", litestore.GTFilter("value", 10), litestore.Filter{Key: "value", Op: litestore.OpGT, Value: 10}},
+		{"GTEFilter", litestore.GTEFilter("value", 10), litestore.Filter{Key: "value", Op: litestore.OpGTE, Value: 10}},
+		{"LTFilter", litestore.LTFilter("value", 10), litestore.Filter{Key: "value", Op: litestore.OpLT, Value: 10}},
+		{"LTEFilter", litestore.LTEFilter("value", 10), litestore.Filter{Key: "value", Op: litestore.OpLTE, Value: 10}},
+		{"InFilter", litestore.InFilter("category", "A", "B"), litestore.Filter{Key: "category", Op: litestore.OpIn, Value: []string{"A", "B"}}},
+		{"NotInFilter", litestore.NotInFilter("value", 1, 2), litestore.Filter{Key: "value", Op: litestore.OpNotIn, Value: []int{1, 2}}},
+		{"InFilter without values", litestore.InFilter[string]("category"), litestore.Filter{Key: "category", Op: litestore.OpIn, Value: []string{}}},
+		{"TypeIsFilter", litestore.TypeIsFilter("value", "integer"), litestore.Filter{Key: "value", Op: litestore.OpTypeIs, Value: "integer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.expected) {
+				t.Errorf("unexpected filter: got %+v, want %+v", tt.got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStore_Querying_NestedKeys(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := newCustomerStore(t, db, "test_entities_nested")
+	ctx := t.Context()
+
+	for _, c := range []*Customer{
+		{Name: "alice", Address: Address{City: "NYC", Zip: "10001"}},
+		{Name: "bob", Address: Address{City: "Boston", Zip: "02101"}},
+		{Name: "charlie", Address: Address{City: "NYC", Zip: "10002"}},
+	} {
+		if err := s.Save(ctx, c); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	queryNames := func(t *testing.T, q *litestore.Query) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, q)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var names []string
+		for c, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	t.Run("filter on nested field", func(t *testing.T) {
+		names := queryNames(t, &litestore.Query{
+			Predicate: litestore.Filter{Key: "address.city", Op: litestore.OpEq, Value: "NYC"},
+		})
+		sort.Strings(names)
+		if expected := []string{"alice", "charlie"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("unexpected results: got %v, want %v", names, expected)
+		}
+	})
+
+	t.Run("IN on nested field", func(t *testing.T) {
+		names := queryNames(t, &litestore.Query{
+			Predicate: litestore.Filter{Key: "address.zip", Op: litestore.OpIn, Value: []string{"02101", "10002"}},
+		})
+		sort.Strings(names)
+		if expected := []string{"bob", "charlie"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("unexpected results: got %v, want %v", names, expected)
+		}
+	})
+
+	t.Run("order by nested field", func(t *testing.T) {
+		names := queryNames(t, &litestore.Query{
+			OrderBy: []litestore.OrderBy{{Key: "address.zip", Direction: litestore.OrderDesc}},
+		})
+		if expected := []string{"charlie", "alice", "bob"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("unexpected order: got %v, want %v", names, expected)
+		}
+	})
+
+	t.Run("unknown nested keys are rejected", func(t *testing.T) {
+		for _, q := range []*litestore.Query{
+			{Predicate: litestore.Filter{Key: "address.country", Op: litestore.OpEq, Value: "US"}},
+			{Predicate: litestore.Filter{Key: "addr.city", Op: litestore.OpEq, Value: "NYC"}},
+			{Predicate: litestore.Filter{Key: "name.first", Op: litestore.OpEq, Value: "alice"}},
+			{OrderBy: []litestore.OrderBy{{Key: "address.country", Direction: litestore.OrderAsc}}},
+		} {
+			if _, err := s.Iter(ctx, q); err == nil {
+				t.Errorf("expected an error for query %+v, got nil", q)
+			}
+		}
+	})
+
+	t.Run("paths into maps and slices are accepted", func(t *testing.T) {
+		type Document struct {
+			ID    string         `litestore:"key"`
+			Attrs map[string]any `json:"attrs"`
+			Items []Address      `json:"items"`
+		}
+		docs, err := litestore.NewStore[Document](ctx, db, "test_entities_nested_docs")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := docs.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		for _, key := range []string{"attrs.color", "attrs.size.width", "items[0].city"} {
+			if _, _, err := docs.CompileQuery(&litestore.Query{Predicate: litestore.Filter{Key: key, Op: litestore.OpEq, Value: "x"}}); err != nil {
+				t.Errorf("expected key %s to be accepted, got %v", key, err)
+			}
+		}
+		if _, _, err := docs.CompileQuery(&litestore.Query{Predicate: litestore.Filter{Key: "items[0].country", Op: litestore.OpEq, Value: "x"}}); err == nil {
+			t.Error("expected an error for an unknown field of a slice element, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_Stream(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_stream")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	for i := range 20 {
+		if err := s.Save(ctx, &TestPersonWithKey{Name: fmt.Sprintf("person-%d", i), Value: i}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	t.Run("sends every matching entity", func(t *testing.T) {
+		ch, err := s.Stream(ctx, &litestore.Query{Predicate: litestore.Filter{Key: "value", Op: litestore.OpLT, Value: 10}})
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sum := 0
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for res := range ch {
+					if res.Err != nil {
+						t.Errorf("stream failed: %v", res.Err)
+						return
+					}
+					mu.Lock()
+					sum += res.Value.Value
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if sum != 45 {
+			t.Errorf("expected values 0..9 to sum to 45, got %d", sum)
+		}
+	})
+
+	t.Run("cancelling the context closes the channel", func(t *testing.T) {
+		streamCtx, cancel := context.WithCancel(ctx)
+		ch, err := s.Stream(streamCtx, nil)
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+
+		<-ch
+		cancel()
+		for range ch {
+			// Drain whatever was in flight; the loop ends once the channel is closed.
+		}
+
+		// The rows were released, so the store is still usable.
+		if _, err := s.GetOne(ctx, litestore.Filter{Key: "value", Op: litestore.OpEq, Value: 0}); err != nil {
+			t.Fatalf("GetOne after cancelled stream failed: %v", err)
+		}
+	})
+
+	t.Run("invalid query returns error", func(t *testing.T) {
+		_, err := s.Stream(ctx, &litestore.Query{Predicate: litestore.Filter{Key: "nope", Op: litestore.OpEq, Value: 1}})
+		if err == nil {
+			t.Fatal("expected an error for an invalid key, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_DefaultOrder(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_default_order",
+		litestore.WithDefaultOrder(litestore.OrderBy{Key: "value", Direction: litestore.OrderDesc}))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for i, name := range []string{"alice", "bob", "charlie"} {
+		if err := s.Save(ctx, &TestPersonWithKey{Name: name, Value: i}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	names := func(t *testing.T, q *litestore.Query) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, q)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var got []string
+		for entity, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, entity.Name)
+		}
+		return got
+	}
+
+	t.Run("applies to queries without order", func(t *testing.T) {
+		want := []string{"charlie", "bob", "alice"}
+		if got := names(t, nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("incorrect order. got: %v, want: %v", got, want)
+		}
+		if got := names(t, &litestore.Query{Limit: 1}); !reflect.DeepEqual(got, want[:1]) {
+			t.Errorf("incorrect order. got: %v, want: %v", got, want[:1])
+		}
+	})
+
+	t.Run("explicit order takes precedence", func(t *testing.T) {
+		q := &litestore.Query{OrderBy: []litestore.OrderBy{{Key: "name", Direction: litestore.OrderAsc}}}
+		want := []string{"alice", "bob", "charlie"}
+		if got := names(t, q); !reflect.DeepEqual(got, want) {
+			t.Errorf("incorrect order. got: %v, want: %v", got, want)
+		}
+	})
+
+	t.Run("invalid default order is rejected", func(t *testing.T) {
+		_, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_default_order",
+			litestore.WithDefaultOrder(litestore.OrderBy{Key: "missing", Direction: litestore.OrderAsc}))
+		if err == nil {
+			t.Fatal("expected an error for an unknown order key, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_MaxScanRows(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_max_scan", litestore.WithMaxScanRows(3))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for i := range 5 {
+		if err := s.Save(ctx, &TestPersonWithKey{Name: fmt.Sprintf("person-%d", i), Value: i}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	count := func(t *testing.T, q *litestore.Query) int {
+		t.Helper()
+		seq, err := s.Iter(ctx, q)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		n := 0
+		for _, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			n++
+		}
+		return n
+	}
+
+	t.Run("queries without a limit are capped", func(t *testing.T) {
+		if n := count(t, nil); n != 3 {
+			t.Errorf("expected 3 entities, got %d", n)
+		}
+		if n := count(t, &litestore.Query{Predicate: litestore.GTEFilter("value", 1)}); n != 3 {
+			t.Errorf("expected 3 entities, got %d", n)
+		}
+	})
+
+	t.Run("explicit limits are kept", func(t *testing.T) {
+		if n := count(t, &litestore.Query{Limit: 5}); n != 5 {
+			t.Errorf("expected 5 entities, got %d", n)
+		}
+		if n := count(t, &litestore.Query{Limit: 2}); n != 2 {
+			t.Errorf("expected 2 entities, got %d", n)
+		}
+	})
+
+	t.Run("page total is not capped", func(t *testing.T) {
+		page, total, err := s.Page(ctx, nil)
+		if err != nil {
+			t.Fatalf("Page failed: %v", err)
+		}
+		if len(page) != 3 || total != 5 {
+			t.Errorf("expected 3 entities of 5, got %d of %d", len(page), total)
+		}
+	})
+
+	t.Run("negative limit is rejected", func(t *testing.T) {
+		if _, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_max_scan", litestore.WithMaxScanRows(-1)); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_OrOfEqualities(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_or_eq")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for i, category := range []string{"A", "B", "C"} {
+		if err := s.Save(ctx, &TestPersonWithKey{Name: fmt.Sprintf("person-%d", i), Category: category}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	names := func(t *testing.T, p litestore.Predicate) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, &litestore.Query{Predicate: p, OrderBy: []litestore.OrderBy{{Key: "name", Direction: litestore.OrderAsc}}})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var got []string
+		for entity, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, entity.Name)
+		}
+		return got
+	}
+
+	t.Run("same key equalities compile to IN", func(t *testing.T) {
+		p := litestore.OrPredicates(litestore.EqFilter("category", "A"), litestore.EqFilter("category", "C"))
+		sql, args, err := s.CompileQuery(&litestore.Query{Predicate: p})
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		if !strings.Contains(sql, `json_extract("json", '$.category') IN (?, ?)`) || len(args) != 2 {
+			t.Errorf("expected an IN clause, got %s %v", sql, args)
+		}
+		if got, want := names(t, p), []string{"person-0", "person-2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("mixed predicates are kept as OR", func(t *testing.T) {
+		p := litestore.OrPredicates(litestore.EqFilter("category", "A"), litestore.EqFilter("name", "person-1"))
+		sql, _, err := s.CompileQuery(&litestore.Query{Predicate: p})
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		if !strings.Contains(sql, " OR ") {
+			t.Errorf("expected an OR clause, got %s", sql)
+		}
+		if got, want := names(t, p), []string{"person-0", "person-1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+}
+
+func TestStore_Querying_StringEncodedNumbers(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Product struct {
+		ID    string  `json:"id" litestore:"key"`
+		Price int64   `json:"price,string"`
+		Ratio float64 `json:"ratio,string"`
+	}
+
+	s, err := litestore.NewStore[Product](ctx, db, "test_products_string_numbers", litestore.WithIndex("price"))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	// Compared as strings, "10" < "100" < "9".
+	for _, p := range []*Product{{ID: "nine", Price: 9, Ratio: 0.9}, {ID: "ten", Price: 10, Ratio: 10}, {ID: "hundred", Price: 100, Ratio: 1.5}} {
+		if err := s.Save(ctx, p); err != nil {
+			t.Fatalf("failed to save product: %v", err)
+		}
+	}
+
+	ids := func(t *testing.T, q *litestore.Query) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, q)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var got []string
+		for p, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, p.ID)
+		}
+		return got
+	}
+
+	t.Run("filters compare numerically", func(t *testing.T) {
+		q := &litestore.Query{
+			Predicate: litestore.GTFilter("price", 9),
+			OrderBy:   []litestore.OrderBy{{Key: "id", Direction: litestore.OrderAsc}},
+		}
+		if got, want := ids(t, q), []string{"hundred", "ten"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		q = &litestore.Query{Predicate: litestore.LTFilter("ratio", 1.0)}
+		if got, want := ids(t, q), []string{"nine"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("ordering is numeric", func(t *testing.T) {
+		q := &litestore.Query{OrderBy: []litestore.OrderBy{{Key: "price", Direction: litestore.OrderAsc}}}
+		if got, want := ids(t, q), []string{"nine", "ten", "hundred"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("index matches the filter expression", func(t *testing.T) {
+		query, args, err := s.CompileQuery(&litestore.Query{Predicate: litestore.EqFilter("price", 10)})
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		if plan := queryPlan(t, db, query, args); !strings.Contains(plan, "idx_test_products_string_numbers_price") {
+			t.Errorf("expected the price index to be used, got plan:\n%s", plan)
+		}
+	})
+}
+
+func TestStore_Querying_IterWithRowID(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_rowid")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, name := range []string{"alice", "bob", "charlie"} {
+		if err := s.Save(ctx, &TestPersonWithKey{K: name, Name: name}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	seq, err := s.IterWithRowID(ctx, &litestore.Query{Predicate: litestore.NEqFilter("name", "bob")})
+	if err != nil {
+		t.Fatalf("IterWithRowID failed: %v", err)
+	}
+	rowIDs := make(map[string]int64)
+	for r, err := range seq {
+		if err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+		if r.Value.K != r.Key {
+			t.Errorf("expected key %s to be populated, got %+v", r.Key, r.Value)
+		}
+		rowIDs[r.Key] = r.RowID
+	}
+	if len(rowIDs) != 2 {
+		t.Fatalf("expected 2 rows, got %v", rowIDs)
+	}
+
+	for key, rowID := range rowIDs {
+		var stored string
+		if err := db.QueryRowContext(ctx, "SELECT key FROM test_entities_rowid WHERE rowid = ?", rowID).Scan(&stored); err != nil {
+			t.Fatalf("failed to look up rowid %d: %v", rowID, err)
+		}
+		if stored != key {
+			t.Errorf("expected rowid %d to hold %s, got %s", rowID, key, stored)
+		}
+	}
+}
+
+func TestStore_Querying_IterRecent(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	t.Run("keyed store", func(t *testing.T) {
+		s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_recent")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		for _, key := range []string{"d", "c", "b", "a"} {
+			if err := s.Save(ctx, &TestPersonWithKey{K: key, Name: key}); err != nil {
+				t.Fatalf("failed to save entity: %v", err)
+			}
+		}
+		// Saving an entity again does not make it recent.
+		if err := s.Save(ctx, &TestPersonWithKey{K: "d", Name: "updated"}); err != nil {
+			t.Fatalf("failed to update entity: %v", err)
+		}
+
+		seq, err := s.IterRecent(ctx, 3)
+		if err != nil {
+			t.Fatalf("IterRecent failed: %v", err)
+		}
+		var got []string
+		for e, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, e.K)
+		}
+		if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("keyless store", func(t *testing.T) {
+		s, err := litestore.NewStore[TestPersonNoKey](ctx, db, "test_entities_recent_no_key")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		for i := range 5 {
+			if err := s.Save(ctx, &TestPersonNoKey{Info: "event", Data: i}); err != nil {
+				t.Fatalf("failed to save entity: %v", err)
+			}
+		}
+
+		seq, err := s.IterRecent(ctx, 2)
+		if err != nil {
+			t.Fatalf("IterRecent failed: %v", err)
+		}
+		var got []int
+		for e, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, e.Data)
+		}
+		if want := []int{4, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("shared table is scoped to the record type", func(t *testing.T) {
+		people, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_recent_shared", litestore.WithRecordType("person"))
+		if err != nil {
+			t.Fatalf("failed to create people store: %v", err)
+		}
+		defer func() {
+			if err := people.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		others, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_recent_shared", litestore.WithRecordType("other"))
+		if err != nil {
+			t.Fatalf("failed to create others store: %v", err)
+		}
+		defer func() {
+			if err := others.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		if err := people.Save(ctx, &TestPersonWithKey{K: "alice"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if err := others.Save(ctx, &TestPersonWithKey{K: "zed"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+
+		seq, err := people.IterRecent(ctx, 10)
+		if err != nil {
+			t.Fatalf("IterRecent failed: %v", err)
+		}
+		var got []string
+		for e, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, e.K)
+		}
+		if want := []string{"alice"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_recent")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if _, err := s.IterRecent(ctx, 0); err == nil {
+			t.Error("expected an error for a zero limit, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_NumericFilterValues(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Reading struct {
+		ID      string  `json:"id" litestore:"key"`
+		Counter uint64  `json:"counter"`
+		Level   float32 `json:"level"`
+	}
+
+	s, err := litestore.NewStore[Reading](ctx, db, "test_readings_numeric_filters")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, r := range []*Reading{
+		{ID: "small", Counter: 30, Level: 0.1},
+		{ID: "max", Counter: math.MaxInt64, Level: 2.5},
+		{ID: "huge", Counter: math.MaxUint64, Level: 7},
+	} {
+		if err := s.Save(ctx, r); err != nil {
+			t.Fatalf("failed to save reading: %v", err)
+		}
+	}
+
+	ids := func(t *testing.T, p litestore.Predicate) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, &litestore.Query{
+			Predicate: p,
+			OrderBy:   []litestore.OrderBy{{Key: "id", Direction: litestore.OrderAsc}},
+		})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var got []string
+		for r, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, r.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name      string
+		predicate litestore.Predicate
+		expected  []string
+	}{
+		{"uint64 at the int64 boundary", litestore.EqFilter("counter", uint64(math.MaxInt64)), []string{"max"}},
+		{"uint64 above the int64 boundary", litestore.GTFilter("counter", uint64(math.MaxInt64)), []string{"huge"}},
+		{"uint64 in an IN list", litestore.InFilter("counter", uint64(30), uint64(math.MaxUint64)), []string{"huge", "small"}},
+		{"small integer types", litestore.EqFilter("counter", int8(30)), []string{"small"}},
+		{"json.Number integer", litestore.EqFilter("counter", json.Number("30")), []string{"small"}},
+		{"json.Number large integer", litestore.EqFilter("counter", json.Number("9223372036854775807")), []string{"max"}},
+		{"json.Number float", litestore.GTEFilter("level", json.Number("2.5")), []string{"huge", "max"}},
+		{"float32 matches its JSON encoding", litestore.EqFilter("level", float32(0.1)), []string{"small"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(t, tt.predicate); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("invalid json.Number is rejected", func(t *testing.T) {
+		_, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.EqFilter("counter", json.Number("thirty"))})
+		if err == nil {
+			t.Fatal("expected an error for an invalid json.Number, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_TupleIn(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Membership struct {
+		ID   string `json:"id" litestore:"key"`
+		Org  string `json:"org"`
+		User string `json:"user"`
+		Role string `json:"role"`
+	}
+
+	s, err := litestore.NewStore[Membership](ctx, db, "test_memberships_tuple_in")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, m := range []*Membership{
+		{ID: "1", Org: "acme", User: "alice", Role: "admin"},
+		{ID: "2", Org: "acme", User: "bob", Role: "member"},
+		{ID: "3", Org: "globex", User: "alice", Role: "member"},
+		{ID: "4", Org: "globex", User: "bob", Role: "admin"},
+	} {
+		if err := s.Save(ctx, m); err != nil {
+			t.Fatalf("failed to save membership: %v", err)
+		}
+	}
+
+	ids := func(t *testing.T, p litestore.Predicate) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, &litestore.Query{Predicate: p, OrderBy: []litestore.OrderBy{{Key: "id", Direction: litestore.OrderAsc}}})
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var got []string
+		for m, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, m.ID)
+		}
+		return got
+	}
+
+	pairs := litestore.TupleIn{
+		Keys:   []string{"org", "user"},
+		Values: [][]any{{"acme", "bob"}, {"globex", "alice"}, {"initech", "alice"}},
+	}
+
+	t.Run("matches the given tuples", func(t *testing.T) {
+		if got, want := ids(t, pairs), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("compiles to a row value comparison", func(t *testing.T) {
+		query, args, err := s.CompileQuery(&litestore.Query{Predicate: pairs})
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		wantSQL := `SELECT "key", "json" FROM "test_memberships_tuple_in" WHERE (json_extract("json", '$.org'), json_extract("json", '$.user')) IN (VALUES (?, ?), (?, ?), (?, ?))`
+		if query != wantSQL {
+			t.Errorf("unexpected SQL:\ngot:  %s\nwant: %s", query, wantSQL)
+		}
+		if want := []any{"acme", "bob", "globex", "alice", "initech", "alice"}; !reflect.DeepEqual(args, want) {
+			t.Errorf("unexpected args: got %v, want %v", args, want)
+		}
+	})
+
+	t.Run("combines with other predicates and the key field", func(t *testing.T) {
+		p := litestore.AndPredicates(
+			litestore.TupleIn{Keys: []string{"id", "role"}, Values: [][]any{{"1", "admin"}, {"2", "admin"}, {"4", "admin"}}},
+			litestore.EqFilter("org", "globex"),
+		)
+		if got, want := ids(t, p), []string{"4"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("empty values match nothing", func(t *testing.T) {
+		if got := ids(t, litestore.TupleIn{Keys: []string{"org", "user"}}); len(got) != 0 {
+			t.Errorf("expected no results, got %v", got)
+		}
+	})
+
+	t.Run("invalid predicates are rejected", func(t *testing.T) {
+		tests := []struct {
+			name string
+			p    litestore.TupleIn
+		}{
+			{"no keys", litestore.TupleIn{Values: [][]any{{"acme"}}}},
+			{"invalid key", litestore.TupleIn{Keys: []string{"org", "nope"}, Values: [][]any{{"acme", "x"}}}},
+			{"short tuple", litestore.TupleIn{Keys: []string{"org", "user"}, Values: [][]any{{"acme"}}}},
+			{"time value", litestore.TupleIn{Keys: []string{"org"}, Values: [][]any{{time.Now()}}}},
+		}
+		for _, tt := range tests {
+			if _, err := s.Iter(ctx, &litestore.Query{Predicate: tt.p}); err == nil {
+				t.Errorf("%s: expected an error, got nil", tt.name)
+			}
+		}
+	})
+}
+
+func TestStore_Querying_ScoreByPredicate(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Product struct {
+		ID    string `json:"id" litestore:"key"`
+		Color string `json:"color"`
+		Size  string `json:"size"`
+		Brand string `json:"brand,omitempty"`
+	}
+
+	s, err := litestore.NewStore[Product](ctx, db, "test_products_score")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, p := range []*Product{
+		{ID: "a", Color: "red", Size: "s", Brand: "acme"},
+		{ID: "b", Color: "red", Size: "m", Brand: "acme"},
+		{ID: "c", Color: "red", Size: "m"},
+		{ID: "d", Color: "blue", Size: "m", Brand: "acme"},
+		{ID: "e", Color: "blue", Size: "l", Brand: "globex"},
+	} {
+		if err := s.Save(ctx, p); err != nil {
+			t.Fatalf("failed to save product: %v", err)
+		}
+	}
+
+	facets := litestore.OrPredicates(
+		litestore.EqFilter("color", "red"),
+		litestore.EqFilter("size", "m"),
+		litestore.EqFilter("brand", "acme"),
+	)
+
+	ids := func(t *testing.T, q *litestore.Query) []string {
+		t.Helper()
+		seq, err := s.Iter(ctx, q)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var got []string
+		for p, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			got = append(got, p.ID)
+		}
+		return got
+	}
+
+	t.Run("orders by the number of matching predicates", func(t *testing.T) {
+		q := &litestore.Query{
+			Predicate:        facets,
+			ScoreByPredicate: true,
+			OrderBy:          []litestore.OrderBy{{Key: "id", Direction: litestore.OrderAsc}},
+		}
+		// b matches all three, a, c and d two (c has no brand), e none.
+		if got, want := ids(t, q), []string{"b", "a", "c", "d"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("limit keeps the most relevant matches", func(t *testing.T) {
+		q := &litestore.Query{Predicate: facets, ScoreByPredicate: true, Limit: 1}
+		if got, want := ids(t, q), []string{"b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("compiles to a score in the order by clause", func(t *testing.T) {
+		q := &litestore.Query{
+			Predicate: litestore.OrPredicates(
+				litestore.EqFilter("color", "red"),
+				litestore.EqFilter("size", "m"),
+			),
+			ScoreByPredicate: true,
+			Limit:            5,
+		}
+		query, args, err := s.CompileQuery(q)
+		if err != nil {
+			t.Fatalf("CompileQuery failed: %v", err)
+		}
+		wantSQL := `SELECT "key", "json" FROM "test_products_score" WHERE (json_extract("json", '$.color') = ?) OR (json_extract("json", '$.size') = ?) ` +
+			`ORDER BY (COALESCE((json_extract("json", '$.color') = ?), 0) + COALESCE((json_extract("json", '$.size') = ?), 0)) DESC LIMIT ?`
+		if query != wantSQL {
+			t.Errorf("unexpected SQL:\ngot:  %s\nwant: %s", query, wantSQL)
+		}
+		if want := []any{"red", "m", "red", "m", 5}; !reflect.DeepEqual(args, want) {
+			t.Errorf("unexpected args: got %v, want %v", args, want)
+		}
+	})
+
+	t.Run("requires a predicate", func(t *testing.T) {
+		if _, err := s.Iter(ctx, &litestore.Query{ScoreByPredicate: true}); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestStore_Querying_TypeIs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Record struct {
+		ID  string `json:"id" litestore:"key"`
+		Age any    `json:"age,omitempty"`
+	}
+
+	s, err := litestore.NewStore[Record](ctx, db, "test_records_type_is")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for _, r := range []*Record{
+		{ID: "int", Age: 30},
+		{ID: "real", Age: 30.5},
+		{ID: "text", Age: "30"},
+		{ID: "true", Age: true},
+		{ID: "false", Age: false},
+		{ID: "array", Age: []int{30}},
+		{ID: "object", Age: map[string]int{"years": 30}},
+		{ID: "missing"},
+	} {
+		if err := s.Save(ctx, r); err != nil {
+			t.Fatalf("failed to save record: %v", err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO "test_records_type_is" ("key", "json") VALUES ('null', '{"id":"null","age":null}')`); err != nil {
+		t.Fatalf("failed to insert record with null age: %v", err)
+	}
+
+	for _, typ := range []string{"null", "true", "false", "integer", "real", "text", "array", "object"} {
+		t.Run(typ, func(t *testing.T) {
+			seq, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.TypeIsFilter("age", typ)})
+			if err != nil {
+				t.Fatalf("Iter failed: %v", err)
+			}
+			var got []string
+			for r, err := range seq {
+				if err != nil {
+					t.Fatalf("iteration failed: %v", err)
+				}
+				got = append(got, r.ID)
+			}
+			want := typ
+			if typ == "integer" {
+				want = "int"
+			}
+			if !reflect.DeepEqual(got, []string{want}) {
+				t.Errorf("expected [%s], got %v", want, got)
+			}
+		})
+	}
+
+	t.Run("invalid type names are rejected", func(t *testing.T) {
+		for _, value := range []any{"number", "INTEGER", 1, nil} {
+			p := litestore.Filter{Key: "age", Op: litestore.OpTypeIs, Value: value}
+			if _, err := s.Iter(ctx, &litestore.Query{Predicate: p}); err == nil {
+				t.Errorf("expected an error for type %v, got nil", value)
+			}
+		}
+	})
+
+	t.Run("invalid key is rejected", func(t *testing.T) {
+		if _, err := s.Iter(ctx, &litestore.Query{Predicate: litestore.TypeIsFilter("nope", "text")}); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
@@ -335,3 +335,77 @@ func TestStore_TransactionStatementLeak(t *testing.T) {
 	// 3. Resource accumulation is bad practice
 	t.Logf("Transaction committed successfully (created ~199 tx-scoped statements)")
 }
+
+func TestStore_WithinTx(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "tx_within")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	t.Run("view runs in the transaction", func(t *testing.T) {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to start transaction: %v", err)
+		}
+		view := s.WithinTx(tx)
+
+		entity := &TestPersonWithKey{Name: "within-tx"}
+		if err := view.Save(ctx, entity); err != nil {
+			t.Fatalf("failed to Save in view: %v", err)
+		}
+		p := litestore.Filter{Key: "k", Op: litestore.OpEq, Value: entity.K}
+
+		if _, err := view.GetOne(ctx, p); err != nil {
+			t.Fatalf("expected to get entity through the view, got err: %v", err)
+		}
+		if _, err := s.GetOne(ctx, p); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected ErrNoRows outside tx, got %v", err)
+		}
+
+		// Closing the view must not close the statements of s.
+		if err := view.Close(); err != nil {
+			t.Errorf("failed to close view: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("failed to commit tx: %v", err)
+		}
+
+		if _, err := s.GetOne(ctx, p); err != nil {
+			t.Fatalf("expected to get entity after commit, got err: %v", err)
+		}
+		if err := s.Delete(ctx, entity.K); err != nil {
+			t.Fatalf("failed to delete entity after closing the view: %v", err)
+		}
+	})
+
+	t.Run("view changes are rolled back with the transaction", func(t *testing.T) {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to start transaction: %v", err)
+		}
+		view := s.WithinTx(tx)
+
+		entity := &TestPersonWithKey{Name: "rolled-back"}
+		if err := view.Save(ctx, entity); err != nil {
+			t.Fatalf("failed to Save in view: %v", err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatalf("failed to roll back tx: %v", err)
+		}
+
+		_, err = s.GetOne(ctx, litestore.Filter{Key: "k", Op: litestore.OpEq, Value: entity.K})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected ErrNoRows after rollback, got %v", err)
+		}
+	})
+}
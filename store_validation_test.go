@@ -1,6 +1,12 @@
 package litestore_test
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/dir01/litestore"
@@ -28,7 +34,7 @@ func TestNewStore_Validation_Errors(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected an error for non-struct type, got nil")
 		}
-		expectedErr := "type T must be a struct, but got int"
+		expectedErr := "type T must be a struct or a map, but got int"
 		if err.Error() != expectedErr {
 			t.Fatalf("expected error '%s', got '%s'", expectedErr, err.Error())
 		}
@@ -81,6 +87,118 @@ func TestNewStore_ValidTableNames(t *testing.T) {
 	}
 }
 
+func TestNewStore_KeywordTableNames(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	for _, tableName := range []string{"order", "index", "select", "group"} {
+		t.Run(tableName, func(t *testing.T) {
+			s, err := litestore.NewStore[TestPersonWithKey](ctx, db, tableName, litestore.WithIndex("name"), litestore.WithRowCounter())
+			if err != nil {
+				t.Fatalf("expected keyword table name '%s' to be usable, got error: %v", tableName, err)
+			}
+			defer func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("failed to close store: %v", err)
+				}
+			}()
+
+			entity := &TestPersonWithKey{Name: "alice"}
+			if err := s.Save(ctx, entity); err != nil {
+				t.Fatalf("failed to save entity: %v", err)
+			}
+			if _, err := s.GetOne(ctx, litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "alice"}); err != nil {
+				t.Fatalf("failed to get entity: %v", err)
+			}
+			if err := s.UpdatePaths(ctx, entity.K, map[string]any{"value": 1}); err != nil {
+				t.Fatalf("failed to update entity: %v", err)
+			}
+			if err := s.Delete(ctx, entity.K); err != nil {
+				t.Fatalf("failed to delete entity: %v", err)
+			}
+			if n, err := s.Len(ctx); err != nil || n != 0 {
+				t.Fatalf("expected empty store, got %d, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestNewStore_QuotedIdentifiers(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	// A keyword table shared by record type, with a versioned schema and an index on a
+	// nested path, whose dot is replaced in the index name.
+	s, err := litestore.NewStore[Customer](ctx, db, "where",
+		litestore.WithRecordType("customer"),
+		litestore.WithSchemaVersion(2),
+		litestore.WithIndex("address.city"),
+		litestore.WithRowCounter(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	c := &Customer{ID: "a", Name: "alice", Address: Address{City: "Paris"}}
+	if err := s.Save(ctx, c); err != nil {
+		t.Fatalf("failed to save customer: %v", err)
+	}
+	if err := s.Rekey(ctx, "a", "b"); err != nil {
+		t.Fatalf("failed to rekey customer: %v", err)
+	}
+	if err := s.UpdateUnset(ctx, "b", "email"); err != nil {
+		t.Fatalf("failed to unset path: %v", err)
+	}
+
+	got, err := s.GetOne(ctx, litestore.Filter{Key: "address.city", Op: litestore.OpEq, Value: "Paris"})
+	if err != nil {
+		t.Fatalf("failed to get customer: %v", err)
+	}
+	if got.ID != "b" {
+		t.Errorf("expected key b, got %s", got.ID)
+	}
+
+	var indexes int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_where_customer_address_city'`).Scan(&indexes)
+	if err != nil {
+		t.Fatalf("failed to query indexes: %v", err)
+	}
+	if indexes != 1 {
+		t.Errorf("expected the nested path index to exist, found %d", indexes)
+	}
+
+	if n, err := s.Len(ctx); err != nil || n != 1 {
+		t.Errorf("expected 1 entity, got %d, %v", n, err)
+	}
+
+	t.Run("table name starting with a digit", func(t *testing.T) {
+		s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "123valid", litestore.WithIndex("name"))
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if err := s.Save(ctx, &TestPersonWithKey{K: "a", Name: "alice"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if _, err := s.GetOne(ctx, litestore.EqFilter("name", "alice")); err != nil {
+			t.Errorf("failed to get entity: %v", err)
+		}
+	})
+}
+
 func TestNewStore_InvalidTableNames(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -93,7 +211,6 @@ func TestNewStore_InvalidTableNames(t *testing.T) {
 		"invalid name",
 		"invalid@name",
 		"",
-		"123invalid",
 		"invalid!name",
 		"invalid+name",
 		"invalid&name",
@@ -153,12 +270,399 @@ func TestNewStore_KeyFieldValidation(t *testing.T) {
 			id string `litestore:"key"` // lowercase = unexported
 		}
 		store, err := litestore.NewStore[UnexportedKeyEntity](ctx, db, "unexported_key_entities")
-		if err != nil {
-			t.Errorf("NewStore should succeed with unexported key field, got error: %v", err)
+		if err == nil {
+			t.Error("NewStore should fail with unexported key field, since Save could never set it")
 		}
 		if store != nil {
 			_ = store.Close()
 		}
-		// The error should occur when trying to Save, not when creating the store
+	})
+}
+
+func TestNewStore_NoJSONFields(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	t.Run("unexported and ignored fields are rejected", func(t *testing.T) {
+		type hiddenEntity struct {
+			name  string
+			Value int `json:"-"`
+		}
+		if _, err := litestore.NewStore[hiddenEntity](ctx, db, "hidden_entities"); err == nil {
+			t.Error("expected an error for a type without JSON fields, got nil")
+		}
+	})
+
+	t.Run("promoted fields of embedded structs count", func(t *testing.T) {
+		type base struct {
+			Name string `json:"name"`
+		}
+		type EmbeddingEntity struct {
+			base
+			secret string
+		}
+		store, err := litestore.NewStore[EmbeddingEntity](ctx, db, "embedding_entities")
+		if err != nil {
+			t.Fatalf("expected promoted fields to be accepted, got error: %v", err)
+		}
+		if err := store.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	})
+
+	t.Run("empty struct is accepted", func(t *testing.T) {
+		store, err := litestore.NewStore[struct{}](ctx, db, "empty_entities")
+		if err != nil {
+			t.Fatalf("expected an empty struct to be accepted, got error: %v", err)
+		}
+		if err := store.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	})
+}
+
+func TestNewStore_WithNoAutoCreate(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	t.Run("missing table fails", func(t *testing.T) {
+		store, err := litestore.NewStore[TestPersonWithKey](ctx, db, "not_created", litestore.WithNoAutoCreate())
+		if err == nil {
+			_ = store.Close()
+			t.Fatal("expected an error for a missing table, got nil")
+		}
+	})
+
+	t.Run("read-only database", func(t *testing.T) {
+		dir := t.TempDir()
+
+		rw, err := sql.Open("sqlite3", fmt.Sprintf("file:%s/ro.db", dir))
+		if err != nil {
+			t.Fatalf("failed to open sqlite: %v", err)
+		}
+		defer rw.Close()
+
+		writer, err := litestore.NewStore[TestPersonWithKey](ctx, rw, "replicated", litestore.WithIndex("name"))
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		if err := writer.Save(ctx, &TestPersonWithKey{K: "a", Name: "alice"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+
+		ro, err := sql.Open("sqlite3", fmt.Sprintf("file:%s/ro.db?mode=ro", dir))
+		if err != nil {
+			t.Fatalf("failed to open sqlite: %v", err)
+		}
+		defer ro.Close()
+
+		// Without the option, the DDL is attempted and fails on a read-only database.
+		if store, err := litestore.NewStore[TestPersonWithKey](ctx, ro, "replicated", litestore.WithIndex("email")); err == nil {
+			_ = store.Close()
+			t.Fatal("expected an error when creating an index on a read-only database, got nil")
+		}
+
+		reader, err := litestore.NewStore[TestPersonWithKey](ctx, ro, "replicated", litestore.WithIndex("email"), litestore.WithNoAutoCreate())
+		if err != nil {
+			t.Fatalf("failed to create read-only store: %v", err)
+		}
+		defer func() {
+			if err := reader.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		got, err := reader.GetOne(ctx, litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "alice"})
+		if err != nil {
+			t.Fatalf("failed to get entity: %v", err)
+		}
+		if got.K != "a" {
+			t.Errorf("unexpected entity: %+v", got)
+		}
+	})
+}
+
+func TestStore_WithValidateJSON(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	// Nested deeper than SQLite's JSON parser allows, but valid for encoding/json.
+	type DeepEntity struct {
+		ID   string          `json:"id" litestore:"key"`
+		Tree json.RawMessage `json:"tree"`
+	}
+	deepTree := json.RawMessage(strings.Repeat("[", 1500) + strings.Repeat("]", 1500))
+
+	t.Run("without the option the document is stored", func(t *testing.T) {
+		s, err := litestore.NewStore[DeepEntity](ctx, db, "unvalidated_entities")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		if err := s.Save(ctx, &DeepEntity{Tree: deepTree}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	})
+
+	t.Run("with the option the document is rejected", func(t *testing.T) {
+		s, err := litestore.NewStore[DeepEntity](ctx, db, "validated_entities", litestore.WithValidateJSON())
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		if err := s.Save(ctx, &DeepEntity{Tree: deepTree}); err == nil {
+			t.Fatal("expected an error for a document SQLite cannot parse, got nil")
+		}
+
+		valid := &DeepEntity{Tree: json.RawMessage(`[[1], [2, 3]]`)}
+		if err := s.Save(ctx, valid); err != nil {
+			t.Fatalf("failed to save valid entity: %v", err)
+		}
+		got, err := s.GetOne(ctx, litestore.Filter{Key: "id", Op: litestore.OpEq, Value: valid.ID})
+		if err != nil {
+			t.Fatalf("failed to get entity: %v", err)
+		}
+		if string(got.Tree) != `[[1],[2,3]]` {
+			t.Errorf("unexpected stored tree: %s", got.Tree)
+		}
+	})
+}
+
+func TestStore_WithDisallowUnknownFields(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	// A newer version of the entity, with a field the older version does not know about.
+	type ProfileV2 struct {
+		ID       string `json:"id" litestore:"key"`
+		Name     string `json:"name"`
+		Nickname string `json:"nickname"`
+	}
+	type Profile struct {
+		ID   string `json:"id" litestore:"key"`
+		Name string `json:"name"`
+	}
+
+	newer, err := litestore.NewStore[ProfileV2](ctx, db, "test_profiles_strict")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := newer.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+	if err := newer.Save(ctx, &ProfileV2{ID: "a", Name: "alice", Nickname: "al"}); err != nil {
+		t.Fatalf("failed to save profile: %v", err)
+	}
+
+	t.Run("unknown fields are ignored by default", func(t *testing.T) {
+		s, err := litestore.NewStore[Profile](ctx, db, "test_profiles_strict")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		got, err := s.GetOne(ctx, litestore.EqFilter("id", "a"))
+		if err != nil {
+			t.Fatalf("GetOne failed: %v", err)
+		}
+		if got.Name != "alice" {
+			t.Errorf("expected alice, got %+v", got)
+		}
+	})
+
+	t.Run("unknown fields fail reads", func(t *testing.T) {
+		s, err := litestore.NewStore[Profile](ctx, db, "test_profiles_strict", litestore.WithDisallowUnknownFields())
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		if _, err := s.GetOne(ctx, litestore.EqFilter("id", "a")); err == nil {
+			t.Error("expected GetOne to fail on an unknown field, got nil")
+		}
+
+		seq, err := s.Iter(ctx, nil)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		var iterErr error
+		for _, err := range seq {
+			if err != nil {
+				iterErr = err
+				break
+			}
+		}
+		if iterErr == nil {
+			t.Error("expected Iter to fail on an unknown field, got nil")
+		}
+
+		if err := s.VerifySchema(ctx, 0); err == nil {
+			t.Error("expected VerifySchema to report the unknown field, got nil")
+		}
+	})
+
+	t.Run("known fields still read", func(t *testing.T) {
+		s, err := litestore.NewStore[ProfileV2](ctx, db, "test_profiles_strict", litestore.WithDisallowUnknownFields())
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		got, err := s.GetOne(ctx, litestore.EqFilter("id", "a"))
+		if err != nil {
+			t.Fatalf("GetOne failed: %v", err)
+		}
+		if got.Nickname != "al" {
+			t.Errorf("expected nickname al, got %+v", got)
+		}
+	})
+
+	t.Run("skipped with an unmarshal error handler", func(t *testing.T) {
+		var skipped []string
+		s, err := litestore.NewStore[Profile](ctx, db, "test_profiles_strict",
+			litestore.WithDisallowUnknownFields(),
+			litestore.WithSkipUnmarshalErrors(func(key string, raw []byte, err error) {
+				skipped = append(skipped, key)
+			}))
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		seq, err := s.Iter(ctx, nil)
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		for _, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+		}
+		if len(skipped) != 1 || skipped[0] != "a" {
+			t.Errorf("expected key a to be skipped, got %v", skipped)
+		}
+	})
+}
+
+func TestNewStore_ConcurrentSchemaSetup(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Document struct {
+		ID       string `json:"id" litestore:"key"`
+		Category string `json:"category"`
+		Body     []byte `json:"body" litestore:"blob"`
+	}
+
+	const n = 16
+	stores := make([]*litestore.Store[Document], n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stores[i], errs[i] = litestore.NewStore[Document](ctx, db, "test_concurrent_setup",
+				litestore.WithIndex("category"),
+				litestore.WithSchemaVersion(2),
+				litestore.WithRowCounter())
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("NewStore %d failed: %v", i, err)
+			continue
+		}
+		if err := stores[i].Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}
+}
+
+func TestStore_WithMaxDocumentSize(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_max_document_size", litestore.WithMaxDocumentSize(200))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	t.Run("small documents are saved", func(t *testing.T) {
+		if err := s.Save(ctx, &TestPersonWithKey{K: "small", Name: "alice"}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	})
+
+	t.Run("large documents are rejected", func(t *testing.T) {
+		large := &TestPersonWithKey{K: "large", Name: strings.Repeat("x", 300)}
+		if err := s.Save(ctx, large); !errors.Is(err, litestore.ErrDocumentTooLarge) {
+			t.Errorf("Save: expected ErrDocumentTooLarge, got %v", err)
+		}
+		if err := s.SaveWithKey(ctx, "large", large); !errors.Is(err, litestore.ErrDocumentTooLarge) {
+			t.Errorf("SaveWithKey: expected ErrDocumentTooLarge, got %v", err)
+		}
+
+		dump := `{"key":"large","data":{"name":"` + strings.Repeat("x", 300) + `"}}`
+		if err := s.Import(ctx, strings.NewReader(dump)); !errors.Is(err, litestore.ErrDocumentTooLarge) {
+			t.Errorf("Import: expected ErrDocumentTooLarge, got %v", err)
+		}
+
+		if _, err := s.GetOne(ctx, litestore.EqFilter("k", "large")); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected the large document not to be stored, got %v", err)
+		}
+	})
+
+	t.Run("negative limit is rejected", func(t *testing.T) {
+		if _, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_max_document_size", litestore.WithMaxDocumentSize(-1)); err == nil {
+			t.Error("expected an error for a negative limit, got nil")
+		}
 	})
 }
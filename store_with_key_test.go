@@ -1,9 +1,12 @@
 package litestore_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/dir01/litestore"
@@ -181,4 +184,669 @@ func TestStore_WithKey_GetOne_Errors(t *testing.T) {
 			t.Fatalf("expected error message '%s', got '%s'", expectedErr, err.Error())
 		}
 	})
+
+	t.Run("get one ordered picks the first of multiple results", func(t *testing.T) {
+		for _, tt := range []struct {
+			direction litestore.OrderDirection
+			expected  string
+		}{
+			{litestore.OrderAsc, "one"},
+			{litestore.OrderDesc, "two"},
+		} {
+			got, err := s.GetOneOrdered(ctx, &litestore.Query{
+				Predicate: litestore.Filter{Key: "category", Op: litestore.OpEq, Value: "A"},
+				OrderBy:   []litestore.OrderBy{{Key: "value", Direction: tt.direction}},
+			})
+			if err != nil {
+				t.Fatalf("GetOneOrdered failed: %v", err)
+			}
+			if got.Name != tt.expected {
+				t.Errorf("%s: expected %s, got %s", tt.direction, tt.expected, got.Name)
+			}
+		}
+	})
+
+	t.Run("get one ordered with no results", func(t *testing.T) {
+		_, err := s.GetOneOrdered(ctx, &litestore.Query{
+			Predicate: litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "non-existent"},
+		})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+}
+
+func TestStore_WithKey_ConflictPolicy(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	newStore := func(t *testing.T, tableName string, policy litestore.ConflictPolicy) *litestore.Store[TestPersonWithKey] {
+		t.Helper()
+		s, err := litestore.NewStore[TestPersonWithKey](ctx, db, tableName, litestore.WithConflictPolicy(policy))
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		t.Cleanup(func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		})
+		return s
+	}
+
+	getName := func(t *testing.T, s *litestore.Store[TestPersonWithKey], key string) string {
+		t.Helper()
+		got, err := s.GetOne(ctx, litestore.Filter{Key: "k", Op: litestore.OpEq, Value: key})
+		if err != nil {
+			t.Fatalf("failed to get entity: %v", err)
+		}
+		return got.Name
+	}
+
+	t.Run("upsert replaces existing entity", func(t *testing.T) {
+		s := newStore(t, "conflict_upsert", litestore.ConflictUpsert)
+		if err := s.Save(ctx, &TestPersonWithKey{K: "dup", Name: "first"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if err := s.Save(ctx, &TestPersonWithKey{K: "dup", Name: "second"}); err != nil {
+			t.Fatalf("failed to save duplicate entity: %v", err)
+		}
+		if name := getName(t, s, "dup"); name != "second" {
+			t.Errorf("expected name 'second', got '%s'", name)
+		}
+	})
+
+	t.Run("fail returns ErrUniqueViolation", func(t *testing.T) {
+		s := newStore(t, "conflict_fail", litestore.ConflictFail)
+		if err := s.Save(ctx, &TestPersonWithKey{K: "dup", Name: "first"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		err := s.Save(ctx, &TestPersonWithKey{K: "dup", Name: "second"})
+		if !errors.Is(err, litestore.ErrUniqueViolation) {
+			t.Fatalf("expected ErrUniqueViolation, got %v", err)
+		}
+		if name := getName(t, s, "dup"); name != "first" {
+			t.Errorf("expected name 'first', got '%s'", name)
+		}
+	})
+
+	t.Run("ignore keeps existing entity", func(t *testing.T) {
+		s := newStore(t, "conflict_ignore", litestore.ConflictIgnore)
+		if err := s.Save(ctx, &TestPersonWithKey{K: "dup", Name: "first"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if err := s.Save(ctx, &TestPersonWithKey{K: "dup", Name: "second"}); err != nil {
+			t.Fatalf("expected no error when ignoring duplicate, got %v", err)
+		}
+		if name := getName(t, s, "dup"); name != "first" {
+			t.Errorf("expected name 'first', got '%s'", name)
+		}
+	})
+}
+
+func TestStore_WithKey_SaveManyFunc(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_save_many", litestore.WithConflictPolicy(litestore.ConflictFail))
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	people := func(keys ...string) []*TestPersonWithKey {
+		var entities []*TestPersonWithKey
+		for _, key := range keys {
+			entities = append(entities, &TestPersonWithKey{K: key, Name: "person " + key})
+		}
+		return entities
+	}
+
+	count := func(t *testing.T) int {
+		t.Helper()
+		_, total, err := s.Page(ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to count entities: %v", err)
+		}
+		return total
+	}
+
+	reset := func(t *testing.T) {
+		t.Helper()
+		if _, err := db.ExecContext(ctx, `DELETE FROM "test_entities_save_many"`); err != nil {
+			t.Fatalf("failed to clear table: %v", err)
+		}
+	}
+
+	t.Run("reports progress after each entity", func(t *testing.T) {
+		defer reset(t)
+		var progress []int
+		err := s.SaveManyFunc(ctx, people("a", "b", "c"), func(done int, err error) bool {
+			if err != nil {
+				t.Errorf("unexpected error for entity %d: %v", done, err)
+			}
+			progress = append(progress, done)
+			return true
+		})
+		if err != nil {
+			t.Fatalf("SaveManyFunc failed: %v", err)
+		}
+		if want := []int{1, 2, 3}; !reflect.DeepEqual(progress, want) {
+			t.Errorf("expected progress %v, got %v", want, progress)
+		}
+		if n := count(t); n != 3 {
+			t.Errorf("expected 3 saved entities, got %d", n)
+		}
+	})
+
+	t.Run("stopping rolls back", func(t *testing.T) {
+		defer reset(t)
+		err := s.SaveManyFunc(ctx, people("a", "b", "c"), func(done int, err error) bool {
+			return done < 2
+		})
+		if !errors.Is(err, litestore.ErrSaveAborted) {
+			t.Fatalf("expected ErrSaveAborted, got %v", err)
+		}
+		if n := count(t); n != 0 {
+			t.Errorf("expected nothing saved, got %d entities", n)
+		}
+	})
+
+	t.Run("failed entities can be skipped", func(t *testing.T) {
+		defer reset(t)
+		var failed []int
+		err := s.SaveManyFunc(ctx, people("a", "a", "b"), func(done int, err error) bool {
+			if err != nil {
+				failed = append(failed, done)
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatalf("SaveManyFunc failed: %v", err)
+		}
+		if want := []int{2}; !reflect.DeepEqual(failed, want) {
+			t.Errorf("expected entity 2 to fail, got failures %v", failed)
+		}
+		if n := count(t); n != 2 {
+			t.Errorf("expected 2 saved entities, got %d", n)
+		}
+	})
+
+	t.Run("without a callback the first error stops the load", func(t *testing.T) {
+		defer reset(t)
+		err := s.SaveManyFunc(ctx, people("a", "b", "a", "c"), nil)
+		if !errors.Is(err, litestore.ErrUniqueViolation) {
+			t.Fatalf("expected ErrUniqueViolation, got %v", err)
+		}
+		if n := count(t); n != 0 {
+			t.Errorf("expected nothing saved, got %d entities", n)
+		}
+	})
+
+	t.Run("canceling the context rolls back", func(t *testing.T) {
+		defer reset(t)
+		cancelCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		err := s.SaveManyFunc(cancelCtx, people("a", "b", "c"), func(done int, err error) bool {
+			if done == 2 {
+				cancel()
+			}
+			return true
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if n := count(t); n != 0 {
+			t.Errorf("expected nothing saved, got %d entities", n)
+		}
+	})
+
+	t.Run("within a transaction", func(t *testing.T) {
+		defer reset(t)
+		err := litestore.WithTransaction(ctx, db, func(txCtx context.Context) error {
+			if err := s.SaveManyFunc(txCtx, people("a", "b"), nil); err != nil {
+				return err
+			}
+			return errors.New("roll back")
+		})
+		if err == nil {
+			t.Fatal("expected the transaction to fail")
+		}
+		if n := count(t); n != 0 {
+			t.Errorf("expected nothing saved after rollback, got %d entities", n)
+		}
+	})
+}
+
+func TestStore_WithKey_Rekey(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_rekey")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	for _, e := range []*TestPersonWithKey{
+		{K: "old", Name: "moving"},
+		{K: "taken", Name: "staying"},
+	} {
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	t.Run("rekey moves entity", func(t *testing.T) {
+		if err := s.Rekey(ctx, "old", "new"); err != nil {
+			t.Fatalf("Rekey failed: %v", err)
+		}
+
+		_, err := s.GetOne(ctx, litestore.Filter{Key: "k", Op: litestore.OpEq, Value: "old"})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows for old key, got %v", err)
+		}
+
+		got, err := s.GetOne(ctx, litestore.Filter{Key: "k", Op: litestore.OpEq, Value: "new"})
+		if err != nil {
+			t.Fatalf("failed to get rekeyed entity: %v", err)
+		}
+		if got.K != "new" || got.Name != "moving" {
+			t.Errorf("unexpected rekeyed entity: %+v", got)
+		}
+
+		// The key stored inside the document must follow the row key.
+		view, err := litestore.IterAs[struct {
+			K string `json:"k"`
+		}](ctx, s, &litestore.Query{Predicate: litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "moving"}})
+		if err != nil {
+			t.Fatalf("IterAs failed: %v", err)
+		}
+		for v, err := range view {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			if v.K != "new" {
+				t.Errorf("expected stored key field to be 'new', got '%s'", v.K)
+			}
+		}
+	})
+
+	t.Run("rekey onto existing key fails", func(t *testing.T) {
+		err := s.Rekey(ctx, "new", "taken")
+		if !errors.Is(err, litestore.ErrUniqueViolation) {
+			t.Fatalf("expected ErrUniqueViolation, got %v", err)
+		}
+	})
+
+	t.Run("rekey of missing entity fails", func(t *testing.T) {
+		err := s.Rekey(ctx, "missing", "whatever")
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+}
+
+func TestStore_WithKey_GetMany(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s, err := litestore.NewStore[TestPersonWithKey](t.Context(), db, "test_entities_getmany")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := t.Context()
+
+	// More entities than fit in a single batch.
+	var keys []string
+	for i := range 1200 {
+		e := &TestPersonWithKey{K: fmt.Sprintf("key-%04d", i), Value: i}
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		keys = append(keys, e.K)
+	}
+
+	t.Run("fetches all existing keys", func(t *testing.T) {
+		got, err := s.GetMany(ctx, append(keys, "missing", keys[0]))
+		if err != nil {
+			t.Fatalf("GetMany failed: %v", err)
+		}
+		if len(got) != len(keys) {
+			t.Fatalf("expected %d entities, got %d", len(keys), len(got))
+		}
+		for i, key := range keys {
+			if got[key].K != key || got[key].Value != i {
+				t.Fatalf("unexpected entity for key %s: %+v", key, got[key])
+			}
+		}
+	})
+
+	t.Run("no keys returns empty result", func(t *testing.T) {
+		got, err := s.GetMany(ctx, nil)
+		if err != nil {
+			t.Fatalf("GetMany failed: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("expected no entities, got %d", len(got))
+		}
+	})
+}
+
+func TestStore_WithKey_WithoutKeyPopulation(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	type EntityWithHiddenKey struct {
+		ID   string `json:"-" litestore:"key"`
+		Name string `json:"name"`
+	}
+
+	ctx := t.Context()
+
+	populating, err := litestore.NewStore[EntityWithHiddenKey](ctx, db, "test_entities_key_population")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := populating.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	plain, err := litestore.NewStore[EntityWithHiddenKey](ctx, db, "test_entities_key_population", litestore.WithoutKeyPopulation())
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := plain.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	entity := &EntityWithHiddenKey{Name: "alice"}
+	if err := populating.Save(ctx, entity); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+
+	p := litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "alice"}
+
+	got, err := populating.GetOne(ctx, p)
+	if err != nil {
+		t.Fatalf("failed to get entity: %v", err)
+	}
+	if got.ID != entity.ID {
+		t.Errorf("expected key %s to be populated, got %q", entity.ID, got.ID)
+	}
+
+	// The key is not part of the json document, so without population it stays empty.
+	got, err = plain.GetOne(ctx, p)
+	if err != nil {
+		t.Fatalf("failed to get entity: %v", err)
+	}
+	if got.ID != "" {
+		t.Errorf("expected key not to be populated, got %q", got.ID)
+	}
+}
+
+func TestStore_WithKey_WithIDGenerator(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	newStore := func(t *testing.T, tableName string) *litestore.Store[TestPersonWithKey] {
+		t.Helper()
+		s, err := litestore.NewStore[TestPersonWithKey](ctx, db, tableName, litestore.WithIDGenerator(litestore.DeterministicIDs(42)))
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		t.Cleanup(func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		})
+		return s
+	}
+
+	saveKeys := func(t *testing.T, s *litestore.Store[TestPersonWithKey]) []string {
+		t.Helper()
+		var keys []string
+		for _, name := range []string{"alice", "bob", "charlie"} {
+			entity := &TestPersonWithKey{Name: name}
+			if err := s.Save(ctx, entity); err != nil {
+				t.Fatalf("failed to save entity: %v", err)
+			}
+			keys = append(keys, entity.K)
+		}
+		return keys
+	}
+
+	first := saveKeys(t, newStore(t, "test_entities_ids_first"))
+	second := saveKeys(t, newStore(t, "test_entities_ids_second"))
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same seed to produce the same keys, got %v and %v", first, second)
+	}
+	seen := make(map[string]bool)
+	for _, k := range first {
+		if seen[k] {
+			t.Errorf("duplicate generated key %s in %v", k, first)
+		}
+		seen[k] = true
+	}
+
+	other := litestore.DeterministicIDs(43)()
+	if other == first[0] {
+		t.Errorf("expected different seeds to produce different keys, both produced %s", other)
+	}
+}
+
+func TestStore_WithKey_NamedStringKey(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type UserID string
+	type User struct {
+		ID   UserID `json:"id" litestore:"key"`
+		Name string `json:"name"`
+	}
+
+	s, err := litestore.NewStore[User](ctx, db, "test_users_named_key")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	generated := &User{Name: "alice"}
+	if err := s.Save(ctx, generated); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	if generated.ID == "" {
+		t.Fatal("expected a generated key to be set")
+	}
+	explicit := &User{ID: UserID("bob-id"), Name: "bob"}
+	if err := s.Save(ctx, explicit); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+
+	got, err := s.GetOne(ctx, litestore.EqFilter("id", explicit.ID))
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if got != *explicit {
+		t.Errorf("expected %+v, got %+v", *explicit, got)
+	}
+
+	many, err := s.GetMany(ctx, []string{string(generated.ID)})
+	if err != nil {
+		t.Fatalf("failed to get users: %v", err)
+	}
+	if many[string(generated.ID)] != *generated {
+		t.Errorf("expected %+v, got %+v", *generated, many)
+	}
+
+	if err := s.Delete(ctx, string(explicit.ID)); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if _, err := s.GetOne(ctx, litestore.EqFilter("id", explicit.ID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected ErrNoRows after delete, got %v", err)
+	}
+}
+
+func TestStore_WithKey_Close(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_close")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	entity := &TestPersonWithKey{Name: "alice"}
+	if err := s.Save(ctx, entity); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	view := s.WithinTx(tx)
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("failed to close store: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("expected closing again to succeed, got %v", err)
+	}
+
+	calls := map[string]func() error{
+		"Save":   func() error { return s.Save(ctx, &TestPersonWithKey{Name: "bob"}) },
+		"Delete": func() error { return s.Delete(ctx, entity.K) },
+		"GetOne": func() error {
+			_, err := s.GetOne(ctx, litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "alice"})
+			return err
+		},
+		"Iter": func() error {
+			_, err := s.Iter(ctx, nil)
+			return err
+		},
+		"UpdatePaths": func() error { return s.UpdatePaths(ctx, entity.K, map[string]any{"value": 1}) },
+		"Ping":        func() error { return s.Ping(ctx) },
+		"view Save":   func() error { return view.Save(ctx, &TestPersonWithKey{Name: "bob"}) },
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			if err := call(); !errors.Is(err, litestore.ErrStoreClosed) {
+				t.Errorf("expected ErrStoreClosed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_WithKey_Ping(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_ping")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	t.Run("usable store", func(t *testing.T) {
+		if err := s.Ping(ctx); err != nil {
+			t.Errorf("expected ping to succeed, got %v", err)
+		}
+		if err := s.Save(ctx, &TestPersonWithKey{Name: "alice"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if err := s.Ping(ctx); err != nil {
+			t.Errorf("expected ping to succeed on a non-empty table, got %v", err)
+		}
+	})
+
+	t.Run("missing table", func(t *testing.T) {
+		if _, err := db.ExecContext(ctx, `DROP TABLE "test_entities_ping"`); err != nil {
+			t.Fatalf("failed to drop table: %v", err)
+		}
+		if err := s.Ping(ctx); err == nil {
+			t.Error("expected ping to fail without the table, got nil")
+		}
+	})
+}
+
+func TestStore_WithKey_ConcurrentClose(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_concurrent_close")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				err := s.Save(ctx, &TestPersonWithKey{Name: fmt.Sprintf("writer-%d-%d", i, j)})
+				if errors.Is(err, litestore.ErrStoreClosed) {
+					return
+				}
+				if err != nil {
+					t.Errorf("expected save to succeed or report ErrStoreClosed, got %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
 }
@@ -23,3 +23,9 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 
 	return db, cleanup
 }
+
+// openBenchDB creates a temporary SQLite database for benchmarks.
+func openBenchDB(b *testing.B) (*sql.DB, error) {
+	b.Helper()
+	return sql.Open("sqlite3", fmt.Sprintf("file:%s/bench.db?_journal_mode=WAL&_synchronous=OFF", b.TempDir()))
+}
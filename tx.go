@@ -54,3 +54,49 @@ func WithTransaction(ctx context.Context, db *sql.DB, fn func(ctx context.Contex
 
 	return nil
 }
+
+// ReadSnapshot executes fn within a read transaction, so that every store call made with
+// the context passed to fn sees the database as it was when ReadSnapshot started, even if
+// other connections commit changes in the meantime. This gives repeatable reads across
+// several calls, e.g. a GetOne followed by an Iter over related entities.
+//
+// The transaction is always rolled back when fn returns, so any changes made within it are
+// discarded. In WAL mode, writers on other connections are not blocked by the snapshot; in
+// the default rollback journal mode, they wait until it ends.
+func ReadSnapshot(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin read transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// SQLite only takes the snapshot at the first read of a transaction, so read right
+	// away rather than at the first store call made by fn.
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&n); err != nil {
+		return fmt.Errorf("failed to start read snapshot: %w", err)
+	}
+
+	return fn(InjectTx(ctx, tx))
+}
+
+// WithinTx returns a view of the store whose methods all run within tx, without the
+// need to inject tx into every context. A transaction carried by the context passed
+// to a method still takes precedence. The view shares the prepared statements of s
+// and is only valid until tx is committed or rolled back.
+func (s *Store[T]) WithinTx(tx *sql.Tx) *Store[T] {
+	view := *s
+	view.tx = tx
+	return &view
+}
+
+// txFor returns the transaction a store method should run in: the one from ctx if
+// there is one, or the store's own transaction otherwise.
+func (s *Store[T]) txFor(ctx context.Context) (*sql.Tx, bool) {
+	if tx, ok := GetTx(ctx); ok {
+		return tx, true
+	}
+	return s.tx, s.tx != nil
+}
@@ -117,3 +117,81 @@ func TestWithTransaction(t *testing.T) {
 		}
 	})
 }
+
+func TestReadSnapshot(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_read_snapshot")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+	if err := s.Save(ctx, &TestPersonWithKey{K: "a", Name: "alice", Value: 1}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+
+	count := func(ctx context.Context) int {
+		t.Helper()
+		_, n, err := s.Page(ctx, nil)
+		if err != nil {
+			t.Fatalf("Page failed: %v", err)
+		}
+		return n
+	}
+
+	t.Run("reads within the snapshot are repeatable", func(t *testing.T) {
+		err := litestore.ReadSnapshot(ctx, db, func(snapCtx context.Context) error {
+			// Changes committed by another connection after the snapshot started.
+			if err := s.Save(ctx, &TestPersonWithKey{K: "b", Name: "bob"}); err != nil {
+				return err
+			}
+			if err := s.Save(ctx, &TestPersonWithKey{K: "a", Name: "alice", Value: 2}); err != nil {
+				return err
+			}
+
+			if n := count(snapCtx); n != 1 {
+				t.Errorf("expected the snapshot to see 1 entity, got %d", n)
+			}
+			alice, err := s.GetOne(snapCtx, litestore.EqFilter("k", "a"))
+			if err != nil {
+				return err
+			}
+			if alice.Value != 1 {
+				t.Errorf("expected the snapshot to see the old value, got %d", alice.Value)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ReadSnapshot failed: %v", err)
+		}
+		if n := count(ctx); n != 2 {
+			t.Errorf("expected 2 entities after the snapshot, got %d", n)
+		}
+	})
+
+	t.Run("returns the error from fn", func(t *testing.T) {
+		errFn := errors.New("fn failed")
+		if err := litestore.ReadSnapshot(ctx, db, func(context.Context) error { return errFn }); !errors.Is(err, errFn) {
+			t.Errorf("expected the error from fn, got %v", err)
+		}
+	})
+
+	t.Run("changes are discarded", func(t *testing.T) {
+		err := litestore.ReadSnapshot(ctx, db, func(snapCtx context.Context) error {
+			return s.Delete(snapCtx, "a")
+		})
+		if err != nil {
+			t.Fatalf("ReadSnapshot failed: %v", err)
+		}
+		if _, err := s.GetOne(ctx, litestore.EqFilter("k", "a")); err != nil {
+			t.Errorf("expected the deleted entity to be kept, got %v", err)
+		}
+	})
+}
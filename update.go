@@ -0,0 +1,208 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// UpdatePaths sets the given JSON paths of the entity with key to new values in a single
+// UPDATE using SQLite's json_set, without reading the entity first. This avoids the race
+// of a read-modify-write cycle and can update nested fields, e.g. "address.city".
+// Paths are relative to the document root, in the same dotted form used by Filter keys.
+// Values are encoded with encoding/json, so they may be any JSON-serializable value,
+// including structs and slices. Missing intermediate objects are created.
+//
+// It returns sql.ErrNoRows if there is no entity with key.
+func (s *Store[T]) UpdatePaths(ctx context.Context, key string, paths map[string]any) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("no paths to update")
+	}
+
+	setExpr, setArgs, err := s.buildJSONSet(paths)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET "json" = %s WHERE %s`, s.table(), setExpr, s.scoped(`"key" = ?`))
+	args := append(setArgs, key)
+
+	res, err := s.execContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("updating entity with key %s: %w", key, err)
+	}
+	if err := checkUpdated(res, key); err != nil {
+		return err
+	}
+	return s.notify(ctx, Mutation{Op: MutationUpdate, Key: key})
+}
+
+// touchField is the JSON field Touch sets to the current time.
+const touchField = "updated_at"
+
+// Touch sets the "updated_at" field of the entity with key to the current time, without
+// reading or rewriting the rest of the document, e.g. to record when an entity was last
+// accessed. T must have a time.Time field encoded as "updated_at"; the time is stored in
+// UTC in the format encoding/json uses for time.Time.
+//
+// It returns an error if T has no such field, and sql.ErrNoRows if there is no entity with key.
+func (s *Store[T]) Touch(ctx context.Context, key string) error {
+	if !s.schema().isTime(touchField) {
+		return fmt.Errorf("touch requires a time.Time field encoded as %q", touchField)
+	}
+	return s.UpdatePaths(ctx, key, map[string]any{touchField: time.Now().UTC()})
+}
+
+// UpdateWhere sets the given JSON paths to new values on every entity matching p in a
+// single UPDATE, and returns the number of entities updated. Paths and values work as in
+// UpdatePaths. A nil predicate updates every entity in the store.
+func (s *Store[T]) UpdateWhere(ctx context.Context, p Predicate, paths map[string]any) (int64, error) {
+	if len(paths) == 0 {
+		return 0, fmt.Errorf("no paths to update")
+	}
+
+	setExpr, args, err := s.buildJSONSet(paths)
+	if err != nil {
+		return 0, err
+	}
+	where, whereArgs, err := (&Query{Predicate: p}).where(s.schema())
+	if err != nil {
+		return 0, fmt.Errorf("building query: %w", err)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET "json" = %s%s`, s.table(), setExpr, where)
+	args = append(args, whereArgs...)
+
+	res, err := s.execContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("updating entities: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("updating entities: %w", err)
+	}
+	return n, nil
+}
+
+// UpdateUnset removes the given JSON paths from the entity with key in a single UPDATE
+// using SQLite's json_remove, e.g. to drop an optional "email" field or a nested
+// "address.zip". Paths that are not present in the document are ignored.
+//
+// It returns sql.ErrNoRows if there is no entity with key.
+func (s *Store[T]) UpdateUnset(ctx context.Context, key string, paths ...string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("no paths to unset")
+	}
+
+	args := make([]any, 0, len(paths)+1)
+	for _, path := range paths {
+		if err := s.validateUpdatePath(path); err != nil {
+			return err
+		}
+		args = append(args, "$."+path)
+	}
+	args = append(args, key)
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(paths)), ", ")
+	query := fmt.Sprintf(`UPDATE %s SET "json" = json_remove("json", %s) WHERE %s`, s.table(), placeholders, s.scoped(`"key" = ?`))
+
+	res, err := s.execContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("updating entity with key %s: %w", key, err)
+	}
+	if err := checkUpdated(res, key); err != nil {
+		return err
+	}
+	return s.notify(ctx, Mutation{Op: MutationUpdate, Key: key})
+}
+
+// MergePatch applies patch, a JSON object, to the entity with key in a single UPDATE using
+// SQLite's json_patch, which implements RFC 7396 merge patch semantics: nested objects are
+// merged recursively, a null value removes the field, and any other value, including an
+// array, replaces the field. For example, {"address": {"city": "Paris", "zip": null}}
+// changes the city, removes the zip code and keeps the rest of the address.
+//
+// The patch cannot change the key field; use Rekey for that. Nor can it change a field
+// tagged with `litestore:"external"` or `litestore:"blob"`, which is not part of the document.
+// It returns sql.ErrNoRows if there is no entity with key.
+func (s *Store[T]) MergePatch(ctx context.Context, key string, patch json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil || fields == nil {
+		return fmt.Errorf("merge patch must be a JSON object: %s", patch)
+	}
+	if _, ok := fields[s.keyFieldJSONName]; ok && s.keyFieldJSONName != "" {
+		return fmt.Errorf("merge patch cannot change the key field %s, use Rekey instead", s.keyFieldJSONName)
+	}
+	for name := range fields {
+		if _, ok := s.externalFields[name]; ok {
+			return fmt.Errorf("merge patch cannot change the external field %s, use Save instead", name)
+		}
+		if s.blobFieldJSONName != "" && name == s.blobFieldJSONName {
+			return fmt.Errorf("merge patch cannot change the blob field %s, use Save instead", name)
+		}
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET "json" = json_patch("json", ?) WHERE %s`, s.table(), s.scoped(`"key" = ?`))
+	res, err := s.execContext(ctx, query, string(patch), key)
+	if err != nil {
+		return fmt.Errorf("updating entity with key %s: %w", key, err)
+	}
+	if err := checkUpdated(res, key); err != nil {
+		return err
+	}
+	return s.notify(ctx, Mutation{Op: MutationUpdate, Key: key})
+}
+
+// buildJSONSet builds a json_set expression that applies paths to the json column.
+// Paths are applied in sorted order so that the generated SQL is stable.
+func (s *Store[T]) buildJSONSet(paths map[string]any) (string, []any, error) {
+	var b strings.Builder
+	var args []any
+
+	b.WriteString(`json_set("json"`)
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		if err := s.validateUpdatePath(path); err != nil {
+			return "", nil, err
+		}
+		value, err := json.Marshal(paths[path])
+		if err != nil {
+			return "", nil, fmt.Errorf("marshaling value for path %s: %w", path, err)
+		}
+		b.WriteString(", ?, json(?)")
+		args = append(args, "$."+path, string(value))
+	}
+	b.WriteString(")")
+
+	return b.String(), args, nil
+}
+
+// validateUpdatePath checks that path may be modified by a partial update.
+func (s *Store[T]) validateUpdatePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("update path cannot be empty")
+	}
+	if s.keyFieldJSONName != "" && path == s.keyFieldJSONName {
+		return fmt.Errorf("cannot update key field '%s', use Rekey instead", path)
+	}
+	if !s.schema().hasKey(path) {
+		return fmt.Errorf("invalid update path: '%s' is not a valid key for this entity", path)
+	}
+	return nil
+}
+
+// checkUpdated returns sql.ErrNoRows if an update of the entity with key affected no rows.
+func checkUpdated(res sql.Result, key string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating entity with key %s: %w", key, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no entity with key %s: %w", key, sql.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package litestore_test
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dir01/litestore"
+)
+
+type Address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type Customer struct {
+	ID      string   `litestore:"key"`
+	Name    string   `json:"name"`
+	Email   string   `json:"email,omitempty"`
+	Tags    []string `json:"tags"`
+	Address Address  `json:"address"`
+	Visits  int      `json:"visits"`
+}
+
+func newCustomerStore(t *testing.T, db *sql.DB, tableName string) *litestore.Store[Customer] {
+	t.Helper()
+	s, err := litestore.NewStore[Customer](t.Context(), db, tableName)
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	})
+	return s
+}
+
+func getCustomer(t *testing.T, s *litestore.Store[Customer], id string) Customer {
+	t.Helper()
+	got, err := s.GetOne(t.Context(), litestore.Filter{Key: "ID", Op: litestore.OpEq, Value: id})
+	if err != nil {
+		t.Fatalf("failed to get customer: %v", err)
+	}
+	return got
+}
+
+func TestStore_UpdatePaths(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := newCustomerStore(t, db, "test_update_paths")
+	ctx := t.Context()
+
+	customer := &Customer{Name: "alice", Tags: []string{"a"}, Address: Address{City: "NYC", Zip: "10001"}, Visits: 1}
+	if err := s.Save(ctx, customer); err != nil {
+		t.Fatalf("failed to save customer: %v", err)
+	}
+
+	t.Run("updates top-level and nested paths", func(t *testing.T) {
+		err := s.UpdatePaths(ctx, customer.ID, map[string]any{
+			"address.city": "Boston",
+			"visits":       2,
+			"tags":         []string{"a", "b"},
+		})
+		if err != nil {
+			t.Fatalf("UpdatePaths failed: %v", err)
+		}
+
+		want := *customer
+		want.Address.City = "Boston"
+		want.Visits = 2
+		want.Tags = []string{"a", "b"}
+		if got := getCustomer(t, s, customer.ID); !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected customer after update.\ngot:  %+v\nwant: %+v", got, want)
+		}
+	})
+
+	t.Run("replaces a whole sub-object", func(t *testing.T) {
+		err := s.UpdatePaths(ctx, customer.ID, map[string]any{"address": Address{City: "Denver"}})
+		if err != nil {
+			t.Fatalf("UpdatePaths failed: %v", err)
+		}
+		if got := getCustomer(t, s, customer.ID); got.Address != (Address{City: "Denver"}) {
+			t.Errorf("unexpected address after update: %+v", got.Address)
+		}
+	})
+
+	t.Run("missing entity returns ErrNoRows", func(t *testing.T) {
+		err := s.UpdatePaths(ctx, "missing", map[string]any{"name": "x"})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("invalid paths are rejected", func(t *testing.T) {
+		for _, path := range []string{"nonexistent", "nonexistent.nested", "ID", ""} {
+			if err := s.UpdatePaths(ctx, customer.ID, map[string]any{path: "x"}); err == nil {
+				t.Errorf("expected an error for path %q, got nil", path)
+			}
+		}
+	})
+}
+
+func TestStore_UpdateWhere(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := newCustomerStore(t, db, "test_update_where")
+	ctx := t.Context()
+
+	var customers []*Customer
+	for _, c := range []*Customer{
+		{Name: "alice", Address: Address{City: "NYC"}, Visits: 1},
+		{Name: "bob", Address: Address{City: "Boston"}, Visits: 2},
+		{Name: "charlie", Address: Address{City: "NYC"}, Visits: 3},
+	} {
+		if err := s.Save(ctx, c); err != nil {
+			t.Fatalf("failed to save customer: %v", err)
+		}
+		customers = append(customers, c)
+	}
+
+	t.Run("updates only matching entities", func(t *testing.T) {
+		n, err := s.UpdateWhere(ctx, litestore.Filter{Key: "address.city", Op: litestore.OpEq, Value: "NYC"}, map[string]any{
+			"tags":   []string{"east"},
+			"visits": 0,
+		})
+		if err != nil {
+			t.Fatalf("UpdateWhere failed: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("expected 2 updated entities, got %d", n)
+		}
+
+		for _, c := range customers {
+			got := getCustomer(t, s, c.ID)
+			if c.Address.City == "NYC" {
+				if got.Visits != 0 || !reflect.DeepEqual(got.Tags, []string{"east"}) {
+					t.Errorf("expected %s to be updated, got %+v", c.Name, got)
+				}
+			} else if !reflect.DeepEqual(got, *c) {
+				t.Errorf("expected %s to be unchanged, got %+v", c.Name, got)
+			}
+		}
+	})
+
+	t.Run("nil predicate updates every entity", func(t *testing.T) {
+		n, err := s.UpdateWhere(ctx, nil, map[string]any{"email": "all@example.com"})
+		if err != nil {
+			t.Fatalf("UpdateWhere failed: %v", err)
+		}
+		if n != int64(len(customers)) {
+			t.Errorf("expected %d updated entities, got %d", len(customers), n)
+		}
+	})
+
+	t.Run("no match updates nothing", func(t *testing.T) {
+		n, err := s.UpdateWhere(ctx, litestore.Filter{Key: "name", Op: litestore.OpEq, Value: "nobody"}, map[string]any{"visits": 9})
+		if err != nil {
+			t.Fatalf("UpdateWhere failed: %v", err)
+		}
+		if n != 0 {
+			t.Errorf("expected 0 updated entities, got %d", n)
+		}
+	})
+
+	t.Run("invalid paths and predicates are rejected", func(t *testing.T) {
+		if _, err := s.UpdateWhere(ctx, nil, map[string]any{"ID": "x"}); err == nil {
+			t.Error("expected an error for updating the key field, got nil")
+		}
+		if _, err := s.UpdateWhere(ctx, litestore.Filter{Key: "nonexistent", Op: litestore.OpEq, Value: 1}, map[string]any{"visits": 1}); err == nil {
+			t.Error("expected an error for an invalid predicate key, got nil")
+		}
+	})
+}
+
+func TestStore_UpdateUnset(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := newCustomerStore(t, db, "test_update_unset")
+	ctx := t.Context()
+
+	customer := &Customer{Name: "alice", Email: "alice@example.com", Address: Address{City: "NYC", Zip: "10001"}}
+	if err := s.Save(ctx, customer); err != nil {
+		t.Fatalf("failed to save customer: %v", err)
+	}
+
+	if err := s.UpdateUnset(ctx, customer.ID, "email", "address.zip"); err != nil {
+		t.Fatalf("UpdateUnset failed: %v", err)
+	}
+
+	got := getCustomer(t, s, customer.ID)
+	if got.Email != "" || got.Address.Zip != "" || got.Address.City != "NYC" || got.Name != "alice" {
+		t.Errorf("unexpected customer after unset: %+v", got)
+	}
+
+	// The field is gone from the document, not just emptied.
+	_, err := s.GetOne(ctx, litestore.Filter{Key: "email", Op: litestore.OpEq, Value: ""})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected no entity with an empty email, got %v", err)
+	}
+
+	t.Run("missing entity returns ErrNoRows", func(t *testing.T) {
+		err := s.UpdateUnset(ctx, "missing", "email")
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("key field cannot be unset", func(t *testing.T) {
+		if err := s.UpdateUnset(ctx, customer.ID, "ID"); err == nil {
+			t.Fatal("expected an error when unsetting the key field, got nil")
+		}
+	})
+}
+
+func TestStore_MergePatch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := newCustomerStore(t, db, "test_merge_patch")
+	ctx := t.Context()
+
+	customer := &Customer{Name: "alice", Email: "alice@example.com", Tags: []string{"a", "b"}, Address: Address{City: "NYC", Zip: "10001"}, Visits: 1}
+	if err := s.Save(ctx, customer); err != nil {
+		t.Fatalf("failed to save customer: %v", err)
+	}
+
+	t.Run("merges nested objects and deletes nulls", func(t *testing.T) {
+		patch := []byte(`{"address": {"zip": null}, "email": null, "tags": ["c"], "visits": 2}`)
+		if err := s.MergePatch(ctx, customer.ID, patch); err != nil {
+			t.Fatalf("MergePatch failed: %v", err)
+		}
+
+		want := Customer{ID: customer.ID, Name: "alice", Tags: []string{"c"}, Address: Address{City: "NYC"}, Visits: 2}
+		if got := getCustomer(t, s, customer.ID); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+
+		var zipType, emailType sql.NullString
+		row := db.QueryRowContext(ctx, `SELECT json_type("json", '$.address.zip'), json_type("json", '$.email') FROM test_merge_patch WHERE "key" = ?`, customer.ID)
+		if err := row.Scan(&zipType, &emailType); err != nil {
+			t.Fatalf("failed to inspect document: %v", err)
+		}
+		if zipType.Valid || emailType.Valid {
+			t.Errorf("expected null fields to be removed, got zip %v and email %v", zipType, emailType)
+		}
+	})
+
+	t.Run("adds new nested fields", func(t *testing.T) {
+		if err := s.MergePatch(ctx, customer.ID, []byte(`{"address": {"zip": "75001", "city": "Paris"}}`)); err != nil {
+			t.Fatalf("MergePatch failed: %v", err)
+		}
+		if got := getCustomer(t, s, customer.ID); got.Address != (Address{City: "Paris", Zip: "75001"}) || got.Name != "alice" {
+			t.Errorf("unexpected customer after patch: %+v", got)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		if err := s.MergePatch(ctx, "nope", []byte(`{"name": "x"}`)); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("invalid patches are rejected", func(t *testing.T) {
+		for _, patch := range []string{`[1, 2]`, `"name"`, `null`, `{"name":`, `{"ID": "other"}`} {
+			if err := s.MergePatch(ctx, customer.ID, []byte(patch)); err == nil {
+				t.Errorf("expected an error for patch %s, got nil", patch)
+			}
+		}
+		if got := getCustomer(t, s, customer.ID); got.Name != "alice" {
+			t.Errorf("expected the customer to be unchanged, got %+v", got)
+		}
+	})
+}
+
+func TestStore_Touch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	type Session struct {
+		ID        string    `json:"id" litestore:"key"`
+		User      string    `json:"user"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	s, err := litestore.NewStore[Session](ctx, db, "test_sessions_touch")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.Save(ctx, &Session{ID: "a", User: "alice", UpdatedAt: old}); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+
+	t.Run("bumps updated_at", func(t *testing.T) {
+		before := time.Now()
+		if err := s.Touch(ctx, "a"); err != nil {
+			t.Fatalf("Touch failed: %v", err)
+		}
+		got, err := s.GetOne(ctx, litestore.EqFilter("id", "a"))
+		if err != nil {
+			t.Fatalf("GetOne failed: %v", err)
+		}
+		if got.UpdatedAt.Before(before) || got.User != "alice" {
+			t.Errorf("expected a fresh updated_at and unchanged fields, got %+v", got)
+		}
+
+		// The stored timestamp can be filtered on like any time.Time field.
+		if _, err := s.GetOne(ctx, litestore.GTFilter("updated_at", old)); err != nil {
+			t.Errorf("expected the touched session to be newer than %v, got %v", old, err)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		if err := s.Touch(ctx, "nope"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("requires an updated_at field", func(t *testing.T) {
+		c := newCustomerStore(t, db, "test_customers_touch")
+		customer := &Customer{Name: "bob"}
+		if err := c.Save(ctx, customer); err != nil {
+			t.Fatalf("failed to save customer: %v", err)
+		}
+		if err := c.Touch(ctx, customer.ID); err == nil {
+			t.Error("expected an error for a type without updated_at, got nil")
+		}
+	})
+
+	t.Run("requires updated_at to be a time", func(t *testing.T) {
+		type Note struct {
+			ID        string `json:"id" litestore:"key"`
+			UpdatedAt string `json:"updated_at"`
+		}
+		n, err := litestore.NewStore[Note](ctx, db, "test_notes_touch")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := n.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if err := n.Save(ctx, &Note{ID: "a", UpdatedAt: "yesterday"}); err != nil {
+			t.Fatalf("failed to save note: %v", err)
+		}
+		if err := n.Touch(ctx, "a"); err == nil || !strings.Contains(err.Error(), "time.Time") {
+			t.Errorf("expected an error for a non-time updated_at, got %v", err)
+		}
+	})
+}
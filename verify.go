@@ -0,0 +1,36 @@
+package litestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// VerifySchema reads up to sampleSize stored documents and checks that each of them
+// unmarshals into T, to surface drift between T and the stored data at startup rather
+// than deep in a request. If sampleSize is zero or negative, every document is checked.
+// Documents are read in storage order, which usually means the oldest ones first.
+//
+// The returned error joins one error per mismatching document, each naming its key and
+// the field that failed. It returns nil if every sampled document matches T. On a store
+// created with WithDisallowUnknownFields, documents with fields T lacks are mismatches too.
+// Unmarshaling every sampled document is costly for large samples, so VerifySchema is
+// meant to be run once at startup, not on every request.
+func (s *Store[T]) VerifySchema(ctx context.Context, sampleSize int) error {
+	seq, err := s.IterRaw(ctx, &Query{Limit: max(sampleSize, 0)})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for pair, err := range seq {
+		if err != nil {
+			return fmt.Errorf("iteration failed while verifying schema: %w", err)
+		}
+		var t T
+		if err := s.unmarshal(string(pair.Value), &t); err != nil {
+			errs = append(errs, fmt.Errorf("entity with key %s does not match %T: %w", pair.Key, t, err))
+		}
+	}
+	return errors.Join(errs...)
+}
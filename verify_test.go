@@ -0,0 +1,63 @@
+package litestore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_VerifySchema(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	// An older version of Customer, which stored visits as a string.
+	type CustomerV1 struct {
+		ID     string `litestore:"key"`
+		Name   string `json:"name"`
+		Visits string `json:"visits"`
+	}
+
+	s := newCustomerStore(t, db, "test_verify_schema")
+
+	t.Run("matching documents pass", func(t *testing.T) {
+		for _, c := range []*Customer{{ID: "a", Name: "alice", Visits: 1}, {ID: "b", Name: "bob", Visits: 2}} {
+			if err := s.Save(ctx, c); err != nil {
+				t.Fatalf("failed to save customer: %v", err)
+			}
+		}
+		if err := s.VerifySchema(ctx, 10); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("outdated documents are reported", func(t *testing.T) {
+		old, err := litestore.NewStore[CustomerV1](ctx, db, "test_verify_schema")
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		defer func() {
+			if err := old.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+		if err := old.Save(ctx, &CustomerV1{ID: "c", Name: "charlie", Visits: "many"}); err != nil {
+			t.Fatalf("failed to save customer: %v", err)
+		}
+
+		err = s.VerifySchema(ctx, 0)
+		if err == nil {
+			t.Fatal("expected an error for an outdated document, got nil")
+		}
+		if msg := err.Error(); !strings.Contains(msg, "key c") || !strings.Contains(msg, "visits") {
+			t.Errorf("expected error to name the key and field, got %v", err)
+		}
+
+		// The outdated document is the last one written, so a smaller sample misses it.
+		if err := s.VerifySchema(ctx, 2); err != nil {
+			t.Errorf("expected no error for a sample of the first documents, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package litestore
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// WithSchemaVersion stamps every row written by Save and Import with version, the version
+// of T's shape that the document was written with, in a schema_version column. Reading the
+// version back with IterVersioned allows migrating documents lazily, one version at a time,
+// instead of rewriting the whole table at once. The version must be positive.
+//
+// The column is added to existing tables; rows written before it existed, or by stores
+// without this option, have version 0.
+func WithSchemaVersion(version int) StoreOption {
+	return func(config *storeConfig) {
+		config.schemaVersion = version
+	}
+}
+
+// Versioned is a stored entity together with its key and the schema version it was saved with.
+type Versioned[V any] struct {
+	Key     string
+	Version int
+	Value   V
+}
+
+// IterVersioned is like Iter, but yields each entity with its key and schema version.
+// It requires the store to be created with WithSchemaVersion.
+func (s *Store[T]) IterVersioned(ctx context.Context, q *Query) (iter.Seq2[Versioned[T], error], error) {
+	if s.schemaVersion == 0 {
+		return nil, fmt.Errorf("schema versions are not enabled for %s, create the store with WithSchemaVersion", s.tableName)
+	}
+	if q == nil {
+		q = &Query{}
+	}
+	querySQL, args, err := q.buildSelect(s.schema(), `COALESCE("schema_version", 0), `+s.schema().columns())
+	if err != nil {
+		return nil, fmt.Errorf("building query: %w", err)
+	}
+	rows, err := s.queryContext(ctx, querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying entities with predicate: %w", err)
+	}
+
+	var version int
+	return iterRowsWith(ctx, rows, []any{&version}, func(key string, jsonData string, blob []byte) (Versioned[T], error) {
+		t, err := s.decode(key, jsonData, blob)
+		if err != nil {
+			return Versioned[T]{}, err
+		}
+		return Versioned[T]{Key: key, Version: version, Value: t}, nil
+	}), nil
+}
+
+// initSchemaVersion adds the schema_version column to the store's table if it is missing.
+func (s *Store[T]) initSchemaVersion(ctx context.Context) error {
+	ok, err := s.hasColumn(ctx, "schema_version")
+	if err != nil || ok {
+		return err
+	}
+	query := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN "schema_version" INTEGER`, s.table())
+	_, err = s.db.ExecContext(ctx, query)
+	return err
+}
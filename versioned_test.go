@@ -0,0 +1,89 @@
+package litestore_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestStore_WithSchemaVersion(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	newStore := func(t *testing.T, options ...litestore.StoreOption) *litestore.Store[TestPersonWithKey] {
+		t.Helper()
+		s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_versioned", options...)
+		if err != nil {
+			t.Fatalf("failed to create new store: %v", err)
+		}
+		t.Cleanup(func() {
+			if err := s.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		})
+		return s
+	}
+
+	// The table is first used without versions, then by two versions of the type.
+	unversioned := newStore(t)
+	if err := unversioned.Save(ctx, &TestPersonWithKey{K: "a", Name: "alice"}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+	v1 := newStore(t, litestore.WithSchemaVersion(1))
+	if err := v1.Save(ctx, &TestPersonWithKey{K: "b", Name: "bob"}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+	v2 := newStore(t, litestore.WithSchemaVersion(2))
+	if err := v2.Save(ctx, &TestPersonWithKey{K: "c", Name: "charlie"}); err != nil {
+		t.Fatalf("failed to save entity: %v", err)
+	}
+
+	versions := func(t *testing.T) map[string]int {
+		t.Helper()
+		seq, err := v2.IterVersioned(ctx, &litestore.Query{OrderBy: []litestore.OrderBy{{Key: "k", Direction: litestore.OrderAsc}}})
+		if err != nil {
+			t.Fatalf("IterVersioned failed: %v", err)
+		}
+		got := make(map[string]int)
+		for v, err := range seq {
+			if err != nil {
+				t.Fatalf("iteration failed: %v", err)
+			}
+			if v.Value.K != v.Key {
+				t.Errorf("expected key %s to be populated, got %+v", v.Key, v.Value)
+			}
+			got[v.Key] = v.Version
+		}
+		return got
+	}
+
+	t.Run("reads the version each row was saved with", func(t *testing.T) {
+		if got, expected := versions(t), map[string]int{"a": 0, "b": 1, "c": 2}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected versions: got %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("saving again stamps the current version", func(t *testing.T) {
+		if err := v2.Save(ctx, &TestPersonWithKey{K: "a", Name: "alice"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+		if got := versions(t)["a"]; got != 2 {
+			t.Errorf("expected version 2 after saving again, got %d", got)
+		}
+	})
+
+	t.Run("requires the option", func(t *testing.T) {
+		if _, err := unversioned.IterVersioned(ctx, nil); err == nil {
+			t.Fatal("expected an error without WithSchemaVersion, got nil")
+		}
+	})
+
+	t.Run("negative version is rejected", func(t *testing.T) {
+		if _, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_versioned", litestore.WithSchemaVersion(-1)); err == nil {
+			t.Fatal("expected an error for a negative version, got nil")
+		}
+	})
+}
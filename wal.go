@@ -0,0 +1,57 @@
+package litestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// CheckpointMode selects how much work Checkpoint does and how it waits for other connections.
+type CheckpointMode string
+
+// Checkpoint modes, see https://www.sqlite.org/pragma.html#pragma_wal_checkpoint.
+const (
+	// CheckpointPassive copies as many frames as possible without waiting for readers or writers.
+	CheckpointPassive CheckpointMode = "PASSIVE"
+	// CheckpointFull waits for writers, then copies every frame of the log into the database.
+	CheckpointFull CheckpointMode = "FULL"
+	// CheckpointRestart is like CheckpointFull, and also waits for readers so that the next
+	// writer starts the log from the beginning.
+	CheckpointRestart CheckpointMode = "RESTART"
+	// CheckpointTruncate is like CheckpointRestart, and also truncates the -wal file to zero bytes.
+	CheckpointTruncate CheckpointMode = "TRUNCATE"
+)
+
+// CheckpointResult reports the outcome of a Checkpoint.
+type CheckpointResult struct {
+	// Busy is true if the checkpoint could not complete because of other connections,
+	// e.g. a long-running read holding on to old frames.
+	Busy bool
+	// LogFrames is the number of frames in the write-ahead log.
+	LogFrames int
+	// CheckpointedFrames is the number of frames of the log copied into the database.
+	CheckpointedFrames int
+}
+
+// Checkpoint copies the content of the write-ahead log of a WAL-mode database back into
+// the database file, e.g. on a schedule to keep the -wal file of a long-running process
+// from growing. SQLite checkpoints automatically as the log grows, but these checkpoints
+// are passive and never shrink the file; use CheckpointTruncate to reclaim its space.
+//
+// A checkpoint that is blocked by other connections is not an error: it is reported with
+// Busy and can be retried later. On a database that is not in WAL mode, Checkpoint does
+// nothing and reports -1 frames.
+func Checkpoint(ctx context.Context, db *sql.DB, mode CheckpointMode) (CheckpointResult, error) {
+	switch mode {
+	case CheckpointPassive, CheckpointFull, CheckpointRestart, CheckpointTruncate:
+	default:
+		return CheckpointResult{}, fmt.Errorf("invalid checkpoint mode: %s", mode)
+	}
+
+	var res CheckpointResult
+	query := fmt.Sprintf("PRAGMA wal_checkpoint(%s)", mode)
+	if err := db.QueryRowContext(ctx, query).Scan(&res.Busy, &res.LogFrames, &res.CheckpointedFrames); err != nil {
+		return CheckpointResult{}, fmt.Errorf("checkpointing write-ahead log: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,70 @@
+package litestore_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dir01/litestore"
+)
+
+func TestCheckpoint(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := t.Context()
+
+	s, err := litestore.NewStore[TestPersonWithKey](ctx, db, "test_entities_checkpoint")
+	if err != nil {
+		t.Fatalf("failed to create new store: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	for range 10 {
+		if err := s.Save(ctx, &TestPersonWithKey{Name: "alice"}); err != nil {
+			t.Fatalf("failed to save entity: %v", err)
+		}
+	}
+
+	t.Run("passive checkpoint copies the log", func(t *testing.T) {
+		res, err := litestore.Checkpoint(ctx, db, litestore.CheckpointPassive)
+		if err != nil {
+			t.Fatalf("Checkpoint failed: %v", err)
+		}
+		if res.Busy || res.LogFrames == 0 || res.CheckpointedFrames != res.LogFrames {
+			t.Errorf("expected every log frame to be checkpointed, got %+v", res)
+		}
+	})
+
+	t.Run("truncate checkpoint empties the wal file", func(t *testing.T) {
+		res, err := litestore.Checkpoint(ctx, db, litestore.CheckpointTruncate)
+		if err != nil {
+			t.Fatalf("Checkpoint failed: %v", err)
+		}
+		if res.Busy {
+			t.Fatalf("expected checkpoint to complete, got %+v", res)
+		}
+
+		var file string
+		if err := db.QueryRowContext(ctx, "SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file); err != nil {
+			t.Fatalf("failed to find database file: %v", err)
+		}
+		info, err := os.Stat(filepath.Clean(file) + "-wal")
+		if err != nil {
+			t.Fatalf("failed to stat wal file: %v", err)
+		}
+		if info.Size() != 0 {
+			t.Errorf("expected an empty wal file, got %d bytes", info.Size())
+		}
+	})
+
+	t.Run("invalid mode is rejected", func(t *testing.T) {
+		if _, err := litestore.Checkpoint(ctx, db, "EVERYTHING"); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}